@@ -0,0 +1,75 @@
+package attach
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/launcher"
+	"github.com/openshift/occ/pkg/session"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewAttachCmd() *cobra.Command {
+	var attachCmd = &cobra.Command{
+		Use:   "attach <session>",
+		Short: "Reattaches to a running occ session",
+		Long: `attach reconnects your terminal to an occ session that is still running, for example after a dropped connection.
+The session can be given by its name or by the cluster ID it was started for. Detach again with ctrl-p,ctrl-q.`,
+		Args: cobra.ExactArgs(1),
+		RunE: attachSession,
+	}
+	return attachCmd
+}
+
+func attachSession(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	eng, err := engine.NewFromConfig(ctx, config.Config)
+	if err != nil {
+		return exitcode.New(exitcode.RuntimeConnection, fmt.Errorf("error building connection to the container engine: %v", err))
+	}
+
+	s, err := session.Find(ctx, eng, args[0])
+	if err != nil {
+		return err
+	}
+
+	signals, stopSignals := launcher.NotifySignals(ctx, eng, s.ID)
+	defer stopSignals()
+	attachErr := make(chan error, 1)
+	go func() {
+		attachErr <- eng.Attach(ctx, s.ID, launcher.DefaultStreams(), nil)
+	}()
+
+	select {
+	case err = <-attachErr:
+	case <-signals.HangUp:
+		log.Debugf("Hung up, leaving session %v running", s.Name)
+		return nil
+	}
+	if errors.Is(err, engine.ErrDetached) {
+		log.Infof("Detached from session %v, run occ attach %v to reattach", s.Name, s.Name)
+		return nil
+	}
+	if err != nil {
+		return exitcode.New(exitcode.ContainerAttach, fmt.Errorf("there was an error attaching to the session: %v", err))
+	}
+
+	containerExitCode, err := eng.Wait(ctx, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the session exit code: %v", err)
+	}
+	if err := session.RemoveSecrets(ctx, eng, s.Name); err != nil {
+		log.Warnf("Unable to clean up after session %v: %v", s.Name, err)
+	}
+	if containerExitCode != exitcode.Success {
+		return exitcode.New(containerExitCode, nil)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/occ/pkg/audit"
+	"github.com/openshift/occ/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+
+	dateLayout = "2006-01-02"
+)
+
+var (
+	cluster string
+	user    string
+	since   string
+	until   string
+	output  string
+)
+
+func NewAuditCmd() *cobra.Command {
+	var auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Lists the sessions recorded in the audit log",
+		Long: `audit lists the sessions occ started on this machine, oldest first, from the audit log.
+occ records every session when it starts and again when it ends, in audit_file, which defaults to audit.jsonl next to the config file.`,
+		Args: cobra.NoArgs,
+		RunE: listSessions,
+	}
+
+	auditCmd.Flags().StringVarP(&cluster, "cluster", "c", "", "Only list sessions on this cluster ID")
+	auditCmd.Flags().StringVarP(&user, "user", "u", "", "Only list sessions of this OCM or local user")
+	auditCmd.Flags().StringVar(&since, "since", "", "Only list sessions started on or after this date (YYYY-MM-DD) or time (RFC 3339)")
+	auditCmd.Flags().StringVar(&until, "until", "", "Only list sessions started before the end of this date (YYYY-MM-DD) or before this time (RFC 3339)")
+	auditCmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format, one of table or json")
+
+	return auditCmd
+}
+
+func listSessions(cmd *cobra.Command, _ []string) error {
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("unknown output format %q, expected %v or %v", output, outputTable, outputJSON)
+	}
+	filter, err := newFilter(time.Local)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	path := audit.FileFromConfig(config.Config, config.DefaultConfigFileLocation)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no audit log at %v, no sessions were recorded yet", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open the audit log: %v", err)
+	}
+	defer f.Close()
+
+	sessions, err := audit.Sessions(f, filter)
+	if err != nil {
+		return err
+	}
+	return printSessions(cmd.OutOrStdout(), sessions, output)
+}
+
+// newFilter builds the filter from the flags. A date given to --until includes that whole day.
+func newFilter(loc *time.Location) (audit.Filter, error) {
+	filter := audit.Filter{ClusterID: cluster, User: user}
+	var err error
+	if since != "" {
+		if filter.Since, _, err = parseTime(since, loc); err != nil {
+			return filter, fmt.Errorf("invalid --since: %v", err)
+		}
+	}
+	if until != "" {
+		var isDate bool
+		if filter.Until, isDate, err = parseTime(until, loc); err != nil {
+			return filter, fmt.Errorf("invalid --until: %v", err)
+		}
+		if isDate {
+			filter.Until = filter.Until.AddDate(0, 0, 1)
+		}
+	}
+	return filter, nil
+}
+
+// parseTime parses a date in loc or an RFC 3339 time, reporting which one it was
+func parseTime(s string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(dateLayout, s, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, false, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 time, got %q", s)
+	}
+	return t, false, nil
+}
+
+func printSessions(out io.Writer, sessions []audit.Record, format string) error {
+	if format == outputJSON {
+		enc := json.NewEncoder(out)
+		for _, s := range sessions {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "START\tDURATION\tCLUSTER\tOCM USER\tUSER\tPROFILE\tEXIT\tSESSION")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", s.Start.Local().Format(time.RFC3339), duration(s), s.ClusterID, s.OCMUser, s.User, s.Profile, outcome(s), s.SessionName)
+	}
+	return w.Flush()
+}
+
+func duration(s audit.Record) string {
+	if s.End == nil {
+		return "-"
+	}
+	return s.End.Sub(s.Start).Round(time.Second).String()
+}
+
+// outcome is the exit code of the session, or why there isn't one
+func outcome(s audit.Record) string {
+	switch {
+	case s.ExitCode != nil:
+		return fmt.Sprint(*s.ExitCode)
+	case s.Detached:
+		return "detached"
+	case s.Error != "":
+		return "failed"
+	case s.End == nil:
+		return "no end recorded"
+	default:
+		return "-"
+	}
+}
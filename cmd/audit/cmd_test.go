@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/occ/pkg/audit"
+)
+
+func TestNewFilter(t *testing.T) {
+	defer func() { cluster, user, since, until = "", "", "", "" }()
+	cluster, user, since, until = "c1", "alice", "2024-05-01", "2024-05-02"
+
+	filter, err := newFilter(time.UTC)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if filter.ClusterID != "c1" || filter.User != "alice" {
+		t.Fatalf("Expected the cluster and user flags, got %+v", filter)
+	}
+	if !filter.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || !filter.Until.Equal(time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected --until to include the whole day, got %v to %v", filter.Since, filter.Until)
+	}
+
+	until = "2024-05-02T12:00:00Z"
+	if filter, err = newFilter(time.UTC); err != nil || !filter.Until.Equal(time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected an exact --until time, got %v %v", filter.Until, err)
+	}
+
+	since = "yesterday"
+	if _, err := newFilter(time.UTC); err == nil {
+		t.Fatalf("Expected an error for an invalid date")
+	}
+}
+
+func TestPrintSessions(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+	exitCode := 3
+	sessions := []audit.Record{
+		{SessionName: "occ-c1", ClusterID: "c1", OCMUser: "alice", Start: start, End: &end, ExitCode: &exitCode},
+		{SessionName: "occ-c2", ClusterID: "c2", OCMUser: "bob", Start: start},
+	}
+
+	var out bytes.Buffer
+	if err := printSessions(&out, sessions, outputTable); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "1h30m0s") || !strings.Contains(lines[1], " 3 ") || !strings.Contains(lines[2], "no end recorded") {
+		t.Fatalf("Unexpected table:\n%v", out.String())
+	}
+
+	out.Reset()
+	if err := printSessions(&out, sessions, outputJSON); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"exit_code":3`) {
+		t.Fatalf("Expected one JSON line per session, got %v", out.String())
+	}
+}
@@ -0,0 +1,240 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/openshift/occ/pkg/launcher"
+	"github.com/openshift/occ/pkg/session"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	// Skip is used when a check can't run because one it depends on failed
+	Skip Status = "skip"
+)
+
+// Result is the outcome of one check, along with how to fix it when it didn't pass
+type Result struct {
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+func checkConfigFile(path string) Result {
+	r := Result{Check: "config"}
+	if _, err := os.Stat(path); err != nil {
+		r.Status, r.Message = Fail, fmt.Sprintf("no config file at %v", path)
+		r.Fix = "Run occ init to create one, or point --config at an existing one"
+		return r
+	}
+	if _, err := config.ReadFile(path); err != nil {
+		r.Status, r.Message = Fail, fmt.Sprintf("%v is not valid YAML: %v", path, err)
+		r.Fix = fmt.Sprintf("Fix the syntax error in %v", path)
+		return r
+	}
+	r.Status, r.Message = Pass, fmt.Sprintf("%v is valid", path)
+	if config.Profile != "" {
+		r.Message += fmt.Sprintf(", using profile %v", config.Profile)
+	}
+	return r
+}
+
+// checkSpec builds the session spec the way occ run would, which validates mounts, providers and env
+func checkSpec(opts launcher.Options) (engine.Spec, Result) {
+	r := Result{Check: "settings"}
+	// The token has its own check, don't unlock the secret store twice
+	opts.OfflineAccessTokenSource = nil
+	spec, err := launcher.NewSpec(opts)
+	if err != nil {
+		r.Status, r.Message = Fail, err.Error()
+		r.Fix = "Fix the setting named above in your config file, see occ run --dry-run for the full session spec"
+		return spec, r
+	}
+	r.Status, r.Message = Pass, "mounts, providers and env are valid"
+	return spec, r
+}
+
+// checkMountSources makes sure every bind mount source exists, as podman refuses to create the container otherwise
+func checkMountSources(spec engine.Spec) Result {
+	r := Result{Check: "mounts"}
+	var missing []string
+	for _, m := range spec.Mounts {
+		if m.Type != define.TypeBind || m.Source == "" {
+			continue
+		}
+		if _, err := os.Stat(m.Source); err != nil {
+			missing = append(missing, m.Source)
+		}
+	}
+	if len(missing) > 0 {
+		r.Status, r.Message = Fail, "mount sources don't exist: "+strings.Join(missing, ", ")
+		r.Fix = "Create them, remove the mount from your config, or disable the provider that adds them"
+		return r
+	}
+	r.Status, r.Message = Pass, fmt.Sprintf("all %d mount sources exist", len(spec.Mounts))
+	return r
+}
+
+// checkRemoteMounts warns about the mounts a remote engine can't use, the rest are copied into the session
+func checkRemoteMounts(spec engine.Spec, host string) Result {
+	r := Result{Check: "remote"}
+	warnings := launcher.RemoteMountWarnings(spec)
+	if len(warnings) > 0 {
+		r.Status, r.Message = Warn, strings.Join(warnings, "; ")
+		r.Fix = "Run occ against a local engine to use local-only mounts, or drop them from your config"
+		return r
+	}
+	r.Status, r.Message = Pass, fmt.Sprintf("%v is remote, mount sources will be copied into the session", host)
+	return r
+}
+
+func checkSSHAgent(goos string, sshAuthSock string, macPrivateTempDir string) Result {
+	r := Result{Check: "ssh-agent"}
+	if goos == "darwin" {
+		dirs, _ := os.ReadDir(macPrivateTempDir)
+		for _, dir := range dirs {
+			if strings.Contains(dir.Name(), "com.apple.launchd") {
+				r.Status, r.Message = Pass, "launchd ssh agent found in "+macPrivateTempDir
+				return r
+			}
+		}
+		r.Status, r.Message = Fail, "no launchd ssh agent found in "+macPrivateTempDir
+		r.Fix = "Make sure the ssh agent is running with ssh-add -l"
+		return r
+	}
+
+	if sshAuthSock == "" {
+		r.Status, r.Message = Fail, "SSH_AUTH_SOCK isn't set"
+		r.Fix = "Start an agent with eval $(ssh-agent) and add your key with ssh-add"
+		return r
+	}
+	if info, err := os.Stat(sshAuthSock); err != nil || info.Mode()&os.ModeSocket == 0 {
+		r.Status, r.Message = Fail, fmt.Sprintf("SSH_AUTH_SOCK points at %v, which isn't a socket", sshAuthSock)
+		r.Fix = "Restart your ssh agent, or update SSH_AUTH_SOCK to point at its socket"
+		return r
+	}
+	r.Status, r.Message = Pass, "ssh agent socket at "+sshAuthSock
+	return r
+}
+
+func checkToken(source func() (string, error)) Result {
+	r := Result{Check: "token"}
+	token, err := source()
+	if err != nil {
+		r.Status, r.Message = Fail, err.Error()
+		r.Fix = "Check secret_store and token_command in your config, or run occ init to store the token again"
+		return r
+	}
+	if token == "" {
+		r.Status, r.Message = Warn, "no offline access token found, sessions won't be logged into OCM"
+		r.Fix = "Run occ init to store your token"
+		return r
+	}
+	r.Status, r.Message = Pass, "offline access token found"
+	return r
+}
+
+func checkEngine(ctx context.Context, opts launcher.Options) (engine.Engine, Result) {
+	r := Result{Check: "engine"}
+	name := opts.EngineName
+	if name == "" {
+		name = engine.Podman
+	}
+
+	eng, err := engine.New(ctx, name, opts.Connection())
+	if err == nil {
+		// Connecting doesn't always reach the engine, listing makes sure it answers
+		_, err = eng.List(ctx, map[string]string{session.ManagedLabel: "true"})
+	}
+	if err != nil {
+		r.Status, r.Message = Fail, fmt.Sprintf("can't reach %v at %v: %v", name, socketName(opts.EngineSocket), err)
+		r.Fix = engineFix(name)
+		return nil, r
+	}
+	r.Status, r.Message = Pass, fmt.Sprintf("connected to %v at %v", name, socketName(opts.EngineSocket))
+	return eng, r
+}
+
+func socketName(socket string) string {
+	if socket == "" {
+		return "the first socket found (-v debug shows which)"
+	}
+	return socket
+}
+
+func engineFix(name string) string {
+	if name == engine.Docker {
+		return fmt.Sprintf("Start Docker, and check %v in your config or DOCKER_HOST", engine.DockerSocketKey)
+	}
+	return fmt.Sprintf("Start the podman socket with systemctl --user enable --now podman.socket, or podman machine start on macOS, and check %v in your config or CONTAINER_HOST", engine.PodmanSocketKey)
+}
+
+func checkImage(ctx context.Context, eng engine.Engine, ref string, policy image.PullPolicy) Result {
+	r := Result{Check: "image"}
+	if eng == nil {
+		r.Status, r.Message = Skip, "no container engine to look for "+ref+" in"
+		return r
+	}
+
+	exists, err := eng.ImageExists(ctx, ref)
+	if err != nil {
+		r.Status, r.Message = Fail, fmt.Sprintf("failed to look for %v: %v", ref, err)
+		return r
+	}
+	if !exists && policy == image.PullNever {
+		r.Status, r.Message = Fail, ref+" isn't available locally and the pull policy is never"
+		r.Fix = "Build it with occ image build, use another tag with --tag, or allow pulls with --pull=missing"
+		return r
+	}
+	if !exists {
+		r.Status, r.Message = Warn, ref+" isn't available locally, it will be pulled when a session starts"
+		r.Fix = "Make sure your registry credentials are in the containers auth.json, or pull it now with podman pull " + ref
+		return r
+	}
+	r.Status, r.Message = Pass, ref+" is available"
+	return r
+}
+
+// checkSignature verifies the image the way occ run does, which needs it to be available locally
+func checkSignature(ctx context.Context, eng engine.Engine, ref string, imageResult Result, opts launcher.Options) Result {
+	r := Result{Check: "signature"}
+	if imageResult.Status != Pass {
+		r.Status, r.Message = Skip, ref+" isn't available to verify"
+		return r
+	}
+
+	digest, err := eng.ImageDigest(ctx, ref)
+	if err == nil {
+		err = opts.Verify.Verify(ctx, ref, digest, opts.AuthFile)
+	}
+	if err != nil {
+		r.Status, r.Message = Fail, fmt.Sprintf("%v isn't signed according to the %v config: %v", ref, image.VerifyKey, err)
+		r.Fix = fmt.Sprintf("Check the policy or key under %v in your config, run an image your build pipeline signed, or pass --skip-verify to occ run to start anyway", image.VerifyKey)
+		return r
+	}
+	r.Status, r.Message = Pass, fmt.Sprintf("%v (%v) is signed", ref, digest)
+	return r
+}
+
+// failed reports whether any check failed
+func failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
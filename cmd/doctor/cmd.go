@@ -0,0 +1,123 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/openshift/occ/pkg/launcher"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var (
+	tag    string
+	output string
+)
+
+func NewDoctorCmd() *cobra.Command {
+	var doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks that occ can launch sessions",
+		Long: `doctor checks the config file, credentials, ssh agent, container engine and image occ run relies on,
+and explains how to fix anything that's wrong. It exits non-zero if any check fails.`,
+		Args: cobra.NoArgs,
+		RunE: runChecks,
+	}
+
+	doctorCmd.Flags().StringVarP(&tag, "tag", "t", "", "Sets the image tag to check for, overriding the configured one")
+	doctorCmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format, one of table or json")
+
+	return doctorCmd
+}
+
+func runChecks(cmd *cobra.Command, _ []string) error {
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("unknown output format %q, expected %v or %v", output, outputTable, outputJSON)
+	}
+	cmd.SilenceUsage = true
+
+	results := checks(context.Background())
+	if err := printResults(cmd.OutOrStdout(), results, output); err != nil {
+		return err
+	}
+	if failed(results) {
+		return exitcode.New(exitcode.Failure, nil)
+	}
+	return nil
+}
+
+func checks(ctx context.Context) []Result {
+	v := config.Config
+	configResult := checkConfigFile(v.ConfigFileUsed())
+	results := []Result{configResult}
+
+	var spec *engine.Spec
+	opts, err := launcher.OptionsFromConfig(v, "")
+	if err != nil {
+		results = append(results, Result{Check: "settings", Status: Fail, Message: err.Error(), Fix: "Fix the section named above in your config file"})
+	} else if configResult.Status == Fail {
+		results = append(results, Result{Check: "settings", Status: Skip, Message: "no valid config file to check"})
+	} else {
+		s, specResult := checkSpec(opts)
+		results = append(results, specResult)
+		if specResult.Status == Pass {
+			spec = &s
+			results = append(results, checkMountSources(s))
+		}
+	}
+
+	results = append(results, checkSSHAgent(runtime.GOOS, opts.SSHAuthSock, launcher.DefaultMacPrivateTempDir))
+	if opts.OfflineAccessTokenSource != nil {
+		results = append(results, checkToken(opts.OfflineAccessTokenSource))
+	}
+
+	eng, engineResult := checkEngine(ctx, opts)
+	results = append(results, engineResult)
+	if eng != nil && spec != nil && engine.IsRemote(eng.Host()) {
+		results = append(results, checkRemoteMounts(*spec, eng.Host()))
+	}
+	ref := opts.Image
+	if tag != "" {
+		if r, err := image.FromConfig(v, tag); err == nil {
+			ref = r.String()
+		}
+	}
+	if ref != "" {
+		imageResult := checkImage(ctx, eng, ref, opts.Pull)
+		results = append(results, imageResult)
+		if opts.Verify.Enabled() {
+			results = append(results, checkSignature(ctx, eng, ref, imageResult, opts))
+		}
+	}
+	return results
+}
+
+func printResults(out io.Writer, results []Result, format string) error {
+	if format == outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tCHECK\tDETAILS")
+	for _, r := range results {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", r.Status, r.Check, r.Message)
+		if r.Fix != "" {
+			fmt.Fprintf(w, "\t\tfix: %v\n", r.Fix)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,216 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/openshift/occ/pkg/launcher"
+)
+
+func TestCheckConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(valid, []byte("ocm_user: test\n"), 0600); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	if err := os.WriteFile(invalid, []byte("ocm_user: [test\n"), 0600); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected Status
+	}{
+		{name: "valid config", path: valid, expected: Pass},
+		{name: "missing config", path: filepath.Join(dir, "missing.yaml"), expected: Fail},
+		{name: "invalid yaml", path: invalid, expected: Fail},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := checkConfigFile(tc.path)
+			if r.Status != tc.expected {
+				t.Fatalf("Expected %v, got %+v", tc.expected, r)
+			}
+			if r.Status == Fail && r.Fix == "" {
+				t.Fatalf("Expected a fix for a failed check")
+			}
+		})
+	}
+}
+
+func TestCheckSSHAgent(t *testing.T) {
+	dir := t.TempDir()
+	notSocket := filepath.Join(dir, "file")
+	if err := os.WriteFile(notSocket, nil, 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on test socket: %v", err)
+	}
+	defer l.Close()
+
+	macDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(macDir, "com.apple.launchd.abc"), 0700); err != nil {
+		t.Fatalf("Failed to create launchd dir: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		goos     string
+		sock     string
+		macDir   string
+		expected Status
+	}{
+		{name: "agent socket", goos: "linux", sock: socket, expected: Pass},
+		{name: "SSH_AUTH_SOCK unset", goos: "linux", expected: Fail},
+		{name: "SSH_AUTH_SOCK isn't a socket", goos: "linux", sock: notSocket, expected: Fail},
+		{name: "SSH_AUTH_SOCK doesn't exist", goos: "linux", sock: filepath.Join(dir, "missing"), expected: Fail},
+		{name: "launchd agent", goos: "darwin", macDir: macDir, expected: Pass},
+		{name: "no launchd agent", goos: "darwin", macDir: dir, expected: Fail},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if r := checkSSHAgent(tc.goos, tc.sock, tc.macDir); r.Status != tc.expected {
+				t.Fatalf("Expected %v, got %+v", tc.expected, r)
+			}
+		})
+	}
+}
+
+func TestCheckMountSources(t *testing.T) {
+	dir := t.TempDir()
+	spec := engine.Spec{Mounts: []specs.Mount{
+		{Type: define.TypeBind, Source: dir, Destination: "/root/exists"},
+		{Type: "tmpfs", Destination: "/tmp"},
+	}}
+	if r := checkMountSources(spec); r.Status != Pass {
+		t.Fatalf("Expected existing sources to pass, got %+v", r)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	spec.Mounts = append(spec.Mounts, specs.Mount{Type: define.TypeBind, Source: missing, Destination: "/root/missing"})
+	r := checkMountSources(spec)
+	if r.Status != Fail || !strings.Contains(r.Message, missing) {
+		t.Fatalf("Expected the missing source to be reported, got %+v", r)
+	}
+}
+
+func TestCheckRemoteMounts(t *testing.T) {
+	dir := t.TempDir()
+	spec := engine.Spec{Mounts: []specs.Mount{{Type: define.TypeBind, Source: dir, Destination: "/root/.aws", Options: []string{"ro"}}}}
+	if r := checkRemoteMounts(spec, "ssh://core@build-host"); r.Status != Pass {
+		t.Fatalf("Expected copyable mounts to pass, got %+v", r)
+	}
+
+	spec.Mounts = append(spec.Mounts, specs.Mount{Type: define.TypeBind, Source: dir, Destination: "/root/sop-utils", Options: []string{"rw"}})
+	if r := checkRemoteMounts(spec, "ssh://core@build-host"); r.Status != Warn || r.Fix == "" {
+		t.Fatalf("Expected a warning for the rw mount, got %+v", r)
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   func() (string, error)
+		expected Status
+	}{
+		{name: "token found", source: func() (string, error) { return "token", nil }, expected: Pass},
+		{name: "no token", source: func() (string, error) { return "", nil }, expected: Warn},
+		{name: "store fails", source: func() (string, error) { return "", errors.New("locked") }, expected: Fail},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if r := checkToken(tc.source); r.Status != tc.expected {
+				t.Fatalf("Expected %v, got %+v", tc.expected, r)
+			}
+		})
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		engine   engine.Engine
+		policy   image.PullPolicy
+		expected Status
+	}{
+		{name: "no engine", expected: Skip},
+		{name: "image exists", engine: &fakeEngine{exists: true}, expected: Pass},
+		{name: "image missing is pulled", engine: &fakeEngine{}, policy: image.PullMissing, expected: Warn},
+		{name: "image missing without pulls", engine: &fakeEngine{}, policy: image.PullNever, expected: Fail},
+		{name: "engine fails", engine: &fakeEngine{err: errors.New("fail")}, expected: Fail},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if r := checkImage(context.Background(), tc.engine, "localhost/ocm-container:latest", tc.policy); r.Status != tc.expected {
+				t.Fatalf("Expected %v, got %+v", tc.expected, r)
+			}
+		})
+	}
+}
+
+func TestCheckSignature(t *testing.T) {
+	opts := launcher.Options{Verify: image.Verification{Key: "/nonexistent/cosign.pub", KeyType: image.KeyTypeSigstore}}
+	if r := checkSignature(context.Background(), &fakeEngine{}, "quay.io/app-sre/ocm-container:latest", Result{Status: Warn}, opts); r.Status != Skip {
+		t.Fatalf("Expected a missing image to skip the check, got %+v", r)
+	}
+	if r := checkSignature(context.Background(), &fakeEngine{exists: true}, "quay.io/app-sre/ocm-container:latest", Result{Status: Pass}, opts); r.Status != Fail {
+		t.Fatalf("Expected a missing key to fail, got %+v", r)
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	results := []Result{
+		{Check: "config", Status: Pass, Message: "valid"},
+		{Check: "token", Status: Warn, Message: "no token", Fix: "Run occ init"},
+	}
+
+	var table bytes.Buffer
+	if err := printResults(&table, results, outputTable); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !strings.Contains(table.String(), "fix: Run occ init") {
+		t.Fatalf("Expected the fix in the table, got %q", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := printResults(&out, results, outputJSON); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if len(decoded) != 2 || decoded[1] != results[1] {
+		t.Fatalf("Expected %+v, got %+v", results, decoded)
+	}
+	if failed(results) {
+		t.Fatalf("Expected a warning not to count as a failure")
+	}
+}
+
+type fakeEngine struct {
+	engine.Engine
+	exists bool
+	err    error
+}
+
+func (f *fakeEngine) ImageExists(context.Context, string) (bool, error) { return f.exists, f.err }
+func (f *fakeEngine) ImageDigest(context.Context, string) (string, error) {
+	return "sha256:test", f.err
+}
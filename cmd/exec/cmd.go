@@ -0,0 +1,89 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/session"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	defaultShell = "/bin/bash"
+)
+
+func NewExecCmd() *cobra.Command {
+	var execCmd = &cobra.Command{
+		Use:   "exec <session> [-- command...]",
+		Short: "Runs a command or opens another shell in a running occ session",
+		Long: `exec runs a command inside an occ session started by occ run, sharing its cluster login and filesystem.
+The session can be given by its name or by the cluster ID it was started for.
+Without a command, a new interactive shell is opened.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: execInSession,
+	}
+
+	// Everything after the session is the command to run, including its flags
+	execCmd.Flags().SetInterspersed(false)
+
+	return execCmd
+}
+
+func execInSession(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	eng, err := engine.NewFromConfig(ctx, config.Config)
+	if err != nil {
+		return exitcode.New(exitcode.RuntimeConnection, fmt.Errorf("error building connection to the container engine: %v", err))
+	}
+
+	s, err := session.Find(ctx, eng, args[0])
+	if err != nil {
+		return err
+	}
+	if s.State != "running" {
+		return fmt.Errorf("session %v is not running (state: %v)", s.Name, s.State)
+	}
+
+	// A TTY would mangle piped input and redirected output, so only use one when both ends are terminals
+	tty := term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+	streams := engine.Streams{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	execExitCode, err := eng.Exec(ctx, s.ID, execConfig(execCommand(args[1:]), tty, os.Getenv("TERM")), streams)
+	if err != nil {
+		return exitcode.New(exitcode.ContainerAttach, fmt.Errorf("there was an error running the command in the session: %v", err))
+	}
+	if execExitCode != exitcode.Success {
+		return exitcode.New(execExitCode, nil)
+	}
+	return nil
+}
+
+// execCommand returns the command to run in the session, defaulting to a new shell
+func execCommand(args []string) []string {
+	if len(args) == 0 {
+		return []string{defaultShell}
+	}
+	return args
+}
+
+func execConfig(command []string, tty bool, termEnv string) engine.ExecConfig {
+	execConfig := engine.ExecConfig{
+		Command: command,
+		Tty:     tty,
+	}
+
+	// Match podman exec -t, which gives the process a terminal type to work with
+	if tty {
+		if termEnv == "" {
+			termEnv = "xterm"
+		}
+		execConfig.Env = []string{"TERM=" + termEnv}
+	}
+	return execConfig
+}
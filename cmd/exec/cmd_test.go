@@ -0,0 +1,54 @@
+package exec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecCommand(t *testing.T) {
+	type test struct {
+		name     string
+		args     []string
+		expected []string
+	}
+
+	tests := []test{
+		{name: "no command opens a shell", args: []string{}, expected: []string{defaultShell}},
+		{name: "command with flags", args: []string{"oc", "get", "nodes", "-o", "wide"}, expected: []string{"oc", "get", "nodes", "-o", "wide"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := execCommand(tc.args); strings.Join(result, " ") != strings.Join(tc.expected, " ") {
+				t.Fatalf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestExecConfig(t *testing.T) {
+	type test struct {
+		name        string
+		tty         bool
+		termEnv     string
+		expectedEnv []string
+	}
+
+	tests := []test{
+		{name: "tty with host terminal type", tty: true, termEnv: "xterm-256color", expectedEnv: []string{"TERM=xterm-256color"}},
+		{name: "tty without host terminal type", tty: true, termEnv: "", expectedEnv: []string{"TERM=xterm"}},
+		{name: "no tty", tty: false, termEnv: "xterm-256color", expectedEnv: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := execConfig([]string{"/bin/bash"}, tc.tty, tc.termEnv)
+			if result.Tty != tc.tty {
+				t.Fatalf("Expected tty to be %v, got %v", tc.tty, result.Tty)
+			}
+			if strings.Join(result.Env, ",") != strings.Join(tc.expectedEnv, ",") {
+				t.Fatalf("Expected env %v, got %v", tc.expectedEnv, result.Env)
+			}
+		})
+	}
+}
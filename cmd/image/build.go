@@ -0,0 +1,212 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildTag      string
+	containerfile string
+	pullBase      bool
+)
+
+func newBuildCmd() *cobra.Command {
+	var buildCmd = &cobra.Command{
+		Use:   "build [context]",
+		Short: "Builds the session image",
+		Long: `build builds the session image from a directory, a Containerfile or a git URL, defaulting to image.build_context in the config file.
+A git URL may end with #<branch or tag> to build that ref. The image is tagged with the configured tag, and with the
+commit it was built from when the context is a git checkout.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runBuild,
+	}
+
+	buildCmd.Flags().StringVarP(&buildTag, "tag", "t", "", "Sets the image tag to build, overriding the configured one")
+	buildCmd.Flags().StringVarP(&containerfile, "file", "f", "", "Path of the Containerfile, relative to the context (default Containerfile or Dockerfile)")
+	buildCmd.Flags().BoolVar(&pullBase, "pull", false, "Pull the base image even if it's available locally")
+
+	return buildCmd
+}
+
+func newUpdateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "update",
+		Short: "Updates the session image",
+		Long: `update pulls the session image again when it comes from a registry. Images in the localhost registry are
+rebuilt from image.build_context instead, with the base image pulled again.`,
+		Args: cobra.NoArgs,
+		RunE: runUpdate,
+	}
+}
+
+func runBuild(cmd *cobra.Command, args []string) error {
+	source := config.Config.GetString(image.BuildContextKey)
+	if len(args) > 0 {
+		source = args[0]
+	}
+	if source == "" {
+		return fmt.Errorf("no build context given, pass one or set %v in your config", image.BuildContextKey)
+	}
+	file := containerfile
+	if file == "" {
+		file = config.Config.GetString(image.ContainerfileKey)
+	}
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	ref, eng, err := connect(ctx, buildTag)
+	if err != nil {
+		return err
+	}
+	return build(ctx, cmd, eng, ref, source, file, pullBase)
+}
+
+func runUpdate(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	ref, eng, err := connect(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if ref.Registry != image.DefaultRegistry {
+		if err := eng.PullImage(ctx, ref.String(), ref.AuthFile, cmd.ErrOrStderr()); err != nil {
+			return fmt.Errorf("failed to pull %v: %v", ref, err)
+		}
+		digest, err := eng.ImageDigest(ctx, ref.String())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%v is at %v\n", ref, digest)
+		return nil
+	}
+
+	source := config.Config.GetString(image.BuildContextKey)
+	if source == "" {
+		return fmt.Errorf("%v is built locally, set %v in your config to rebuild it", ref, image.BuildContextKey)
+	}
+	return build(ctx, cmd, eng, ref, source, config.Config.GetString(image.ContainerfileKey), true)
+}
+
+func build(ctx context.Context, cmd *cobra.Command, eng engine.Engine, ref image.Ref, source string, file string, pullBase bool) error {
+	contextDir, cleanup, err := checkout(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	file, err = findContainerfile(contextDir, file)
+	if err != nil {
+		return err
+	}
+
+	// Builds can't be pinned to a digest, so only the tag is kept
+	ref.Digest = ""
+	tags := []string{ref.String()}
+	if revision := gitRevision(ctx, contextDir); revision != "" && revision != ref.Tag {
+		tags = append(tags, ref.Name()+":"+revision)
+	}
+
+	log.Infof("Building %v from %v", strings.Join(tags, ", "), source)
+	id, err := eng.BuildImage(ctx, engine.BuildOptions{
+		ContextDir:    contextDir,
+		Containerfile: file,
+		Tags:          tags,
+		PullBase:      pullBase,
+		AuthFile:      ref.AuthFile,
+	}, cmd.ErrOrStderr())
+	if err != nil {
+		return fmt.Errorf("failed to build %v: %v", ref, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Built %v as %v\n", id, strings.Join(tags, ", "))
+	return nil
+}
+
+// isGitURL reports whether the build context is a git repository to clone rather than a local path
+func isGitURL(source string) bool {
+	for _, prefix := range []string{"git://", "git@", "ssh://", "https://", "http://"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkout returns the local directory to build from. Git URLs are shallow cloned into a temp dir,
+// which cleanup removes.
+func checkout(ctx context.Context, source string) (string, func(), error) {
+	if !isGitURL(source) {
+		info, err := os.Stat(source)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid build context: %v", err)
+		}
+		if !info.IsDir() {
+			// A Containerfile was given, its directory is the context
+			return filepath.Dir(source), func() {}, nil
+		}
+		return source, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "occ_build")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create a tempdir to clone into: %v", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	url, ref, _ := strings.Cut(source, "#")
+	args := []string{"clone", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	clone := exec.CommandContext(ctx, "git", append(args, url, dir)...)
+	if output, err := clone.CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to clone %v: %v: %v", source, err, strings.TrimSpace(string(output)))
+	}
+	return dir, cleanup, nil
+}
+
+// findContainerfile resolves the Containerfile in the context, looking for Containerfile then Dockerfile when none is given
+func findContainerfile(contextDir string, file string) (string, error) {
+	if file != "" {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(contextDir, file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return "", fmt.Errorf("invalid Containerfile: %v", err)
+		}
+		return file, nil
+	}
+
+	for _, name := range []string{"Containerfile", "Dockerfile"} {
+		file := filepath.Join(contextDir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no Containerfile or Dockerfile found in %v", contextDir)
+}
+
+// gitRevision returns the short commit the directory is checked out at, if it's a git checkout
+func gitRevision(ctx context.Context, dir string) string {
+	output, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
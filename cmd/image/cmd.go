@@ -0,0 +1,43 @@
+package image
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/spf13/cobra"
+)
+
+func NewImageCmd() *cobra.Command {
+	var imageCmd = &cobra.Command{
+		Use:   "image",
+		Short: "Manages the session image",
+		Long: `image builds, updates, lists and prunes the image occ sessions run, as set by image in the config file.
+It uses the same container engine as occ run.`,
+		Args: cobra.NoArgs,
+	}
+
+	imageCmd.AddCommand(
+		newBuildCmd(),
+		newUpdateCmd(),
+		newLsCmd(),
+		newPruneCmd(),
+	)
+	return imageCmd
+}
+
+// connect returns the configured image and a connection to the engine occ run would use
+func connect(ctx context.Context, tag string) (image.Ref, engine.Engine, error) {
+	ref, err := image.FromConfig(config.Config, tag)
+	if err != nil {
+		return ref, nil, err
+	}
+	eng, err := engine.NewFromConfig(ctx, config.Config)
+	if err != nil {
+		return ref, nil, exitcode.New(exitcode.RuntimeConnection, fmt.Errorf("error building connection to the container engine: %v", err))
+	}
+	return ref, eng, nil
+}
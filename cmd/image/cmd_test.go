@@ -0,0 +1,205 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/spf13/cobra"
+)
+
+func TestPruneTags(t *testing.T) {
+	now := time.Now()
+	images := []engine.Image{
+		{ID: "old", Tags: []string{"localhost/ocm-container:abc123"}, Created: now.Add(-72 * time.Hour)},
+		{ID: "newest", Tags: []string{"localhost/ocm-container:latest", "localhost/ocm-container:def456"}, Created: now},
+		{ID: "oldest", Tags: []string{"localhost/ocm-container:stable"}, Created: now.Add(-96 * time.Hour)},
+		{ID: "newer", Tags: []string{"localhost/ocm-container:fed789"}, Created: now.Add(-24 * time.Hour)},
+	}
+
+	images[2].Digest = "sha256:pinned"
+
+	type test struct {
+		name     string
+		keep     int
+		current  inUse
+		expected []string
+	}
+
+	tests := []test{
+		{name: "keeps the newest", keep: 2, current: inUse{tags: []string{"localhost/ocm-container:latest"}}, expected: []string{"localhost/ocm-container:abc123", "localhost/ocm-container:stable"}},
+		{name: "keeps the current image", keep: 1, current: inUse{tags: []string{"localhost/ocm-container:stable"}}, expected: []string{"localhost/ocm-container:fed789", "localhost/ocm-container:abc123"}},
+		{name: "keeps the pinned digest", keep: 1, current: inUse{ids: []string{"sha256:pinned"}}, expected: []string{"localhost/ocm-container:fed789", "localhost/ocm-container:abc123"}},
+		{name: "keeps the current image by ID", keep: 1, current: inUse{ids: []string{"sha256:old"}}, expected: []string{"localhost/ocm-container:fed789", "localhost/ocm-container:stable"}},
+		{name: "keeps everything", keep: 10, current: inUse{tags: []string{"localhost/ocm-container:latest"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tags := pruneTags(images, tc.keep, tc.current)
+			if strings.Join(tags, " ") != strings.Join(tc.expected, " ") {
+				t.Fatalf("Expected %v to be removed, got %v", tc.expected, tags)
+			}
+		})
+	}
+}
+
+func TestImagesInUse(t *testing.T) {
+	eng := &fakeEngine{digest: "sha256:base"}
+	pinned := image.Ref{Registry: "localhost", Repository: "ocm-container", Digest: "sha256:pinned"}
+	if u := imagesInUse(context.Background(), eng, pinned, image.Customization{}); len(u.tags) != 0 || strings.Join(u.ids, " ") != "sha256:pinned sha256:base" {
+		t.Fatalf("Expected a pinned image to be kept by digest, got %+v", u)
+	}
+
+	tagged := image.Ref{Registry: "localhost", Repository: "ocm-container", Tag: "latest"}
+	custom := image.Customization{Packages: []string{"neovim"}}
+	customRef, _, err := custom.Ref(tagged.String(), "sha256:base")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	u := imagesInUse(context.Background(), eng, tagged, custom)
+	if strings.Join(u.tags, " ") != "localhost/ocm-container:latest "+customRef || u.keepCustom {
+		t.Fatalf("Expected the image and its customization %v to be kept, got %+v", customRef, u)
+	}
+	customImage := engine.Image{ID: "custom", Tags: []string{customRef}}
+	if tags := pruneTags([]engine.Image{customImage}, 0, u); len(tags) != 0 {
+		t.Fatalf("Expected the customization in use to be kept, got %v", tags)
+	}
+
+	custom.Containerfile = filepath.Join(t.TempDir(), "missing")
+	if u := imagesInUse(context.Background(), eng, tagged, custom); !u.keepCustom {
+		t.Fatalf("Expected every customization to be kept when the one in use can't be resolved, got %+v", u)
+	}
+}
+
+func TestFindContainerfile(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := findContainerfile(dir, ""); err == nil {
+		t.Fatalf("Expected an error without a Containerfile")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM fedora"), 0600); err != nil {
+		t.Fatalf("Failed to write Dockerfile: %v", err)
+	}
+	if file, err := findContainerfile(dir, ""); err != nil || file != filepath.Join(dir, "Dockerfile") {
+		t.Fatalf("Expected the Dockerfile, got %v %v", file, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Containerfile"), []byte("FROM fedora"), 0600); err != nil {
+		t.Fatalf("Failed to write Containerfile: %v", err)
+	}
+	if file, err := findContainerfile(dir, ""); err != nil || file != filepath.Join(dir, "Containerfile") {
+		t.Fatalf("Expected the Containerfile to be preferred, got %v %v", file, err)
+	}
+	if file, err := findContainerfile(dir, "Dockerfile"); err != nil || file != filepath.Join(dir, "Dockerfile") {
+		t.Fatalf("Expected the given file relative to the context, got %v %v", file, err)
+	}
+	if _, err := findContainerfile(dir, "Missingfile"); err == nil {
+		t.Fatalf("Expected an error for a missing file")
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "Containerfile")
+	if err := os.WriteFile(file, []byte("FROM fedora"), 0600); err != nil {
+		t.Fatalf("Failed to write Containerfile: %v", err)
+	}
+
+	for source, expected := range map[string]string{dir: dir, file: dir} {
+		contextDir, cleanup, err := checkout(context.Background(), source)
+		if err != nil || contextDir != expected {
+			t.Fatalf("Expected %v to build in %v, got %v %v", source, expected, contextDir, err)
+		}
+		cleanup()
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("Expected a local context to be left alone")
+	}
+
+	if _, _, err := checkout(context.Background(), filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("Expected an error for a missing context")
+	}
+}
+
+func TestIsGitURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/openshift/ocm-container.git":   true,
+		"git@github.com:openshift/ocm-container.git#v1.0":  true,
+		"ssh://git@github.com/openshift/ocm-container.git": true,
+		"/home/me/git/ocm-container":                       false,
+		"ocm-container/Containerfile":                      false,
+	}
+	for source, expected := range tests {
+		if isGitURL(source) != expected {
+			t.Errorf("Expected isGitURL(%q) to be %v", source, expected)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Containerfile"), []byte("FROM fedora"), 0600); err != nil {
+		t.Fatalf("Failed to write Containerfile: %v", err)
+	}
+
+	eng := &fakeEngine{}
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	ref := image.Ref{Registry: "localhost", Repository: "ocm-container", Tag: "test", Digest: "sha256:pinned"}
+	if err := build(context.Background(), cmd, eng, ref, dir, "", true); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if eng.built.ContextDir != dir || eng.built.Containerfile != filepath.Join(dir, "Containerfile") || !eng.built.PullBase {
+		t.Fatalf("Unexpected build options %+v", eng.built)
+	}
+	if eng.built.Tags[0] != "localhost/ocm-container:test" {
+		t.Fatalf("Expected the image to be tagged without its digest, got %v", eng.built.Tags)
+	}
+	if !strings.Contains(out.String(), "Built test-id") {
+		t.Fatalf("Expected the built image to be printed, got %q", out.String())
+	}
+}
+
+func TestPrintImages(t *testing.T) {
+	now := time.Now()
+	images := []engine.Image{{
+		ID:      "sha256:0123456789abcdef0123",
+		Tags:    []string{"localhost/ocm-container:latest", "localhost/ocm-container:abc123"},
+		Digest:  "sha256:fedcba9876543210fedc",
+		Created: now.Add(-time.Hour),
+	}}
+
+	var out bytes.Buffer
+	if err := printImages(&out, images, now); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	for _, expected := range []string{"latest,abc123", "sha256:fedcba987654", "0123456789ab", "1h0m0s ago"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("Expected %q in %q", expected, out.String())
+		}
+	}
+}
+
+type fakeEngine struct {
+	engine.Engine
+	built  engine.BuildOptions
+	digest string
+}
+
+func (f *fakeEngine) ImageDigest(context.Context, string) (string, error) { return f.digest, nil }
+
+func (f *fakeEngine) BuildImage(_ context.Context, options engine.BuildOptions, _ io.Writer) (string, error) {
+	f.built = options
+	return "test-id", nil
+}
@@ -0,0 +1,84 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+func newLsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "Lists the local session images",
+		Long:  `ls lists the local images in the configured image repository, newest first, with their digest and age.`,
+		Args:  cobra.NoArgs,
+		RunE:  listImages,
+	}
+}
+
+func listImages(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	ref, eng, err := connect(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	images, err := eng.ListImages(ctx, ref.Name())
+	if err != nil {
+		return fmt.Errorf("failed to list images: %v", err)
+	}
+	sortNewestFirst(images)
+	return printImages(cmd.OutOrStdout(), images, time.Now())
+}
+
+func sortNewestFirst(images []engine.Image) {
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Created.After(images[j].Created) })
+}
+
+func printImages(out io.Writer, images []engine.Image, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TAG\tDIGEST\tID\tCREATED")
+	for _, i := range images {
+		var tags []string
+		for _, tag := range i.Tags {
+			tags = append(tags, tag[strings.LastIndex(tag, ":")+1:])
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v ago\n", strings.Join(tags, ","), shortDigest(i.Digest), shortID(i.ID), now.Sub(i.Created).Round(time.Second))
+	}
+	return w.Flush()
+}
+
+// shortDigest shortens a digest or ID the way podman and docker print them, keeping the algorithm of digests
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "<none>"
+	}
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		algorithm, hex = "", digest
+	}
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	if algorithm == "" {
+		return hex
+	}
+	return algorithm + ":" + hex
+}
+
+// shortID shortens an image ID, which docker prefixes with its algorithm and podman doesn't
+func shortID(id string) string {
+	if _, hex, ok := strings.Cut(id, ":"); ok {
+		id = hex
+	}
+	return shortDigest(id)
+}
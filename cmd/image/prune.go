@@ -0,0 +1,159 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var keep int
+
+func newPruneCmd() *cobra.Command {
+	var pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Removes old session images",
+		Long: `prune removes the local images in the configured image repository, except the newest ones and the configured image.
+Old customizations of the image are pruned the same way, except the one occ run would use. Images still used by a session are kept.`,
+		Args: cobra.NoArgs,
+		RunE: pruneImages,
+	}
+
+	pruneCmd.Flags().IntVar(&keep, "keep", 3, "Number of the newest images to keep")
+
+	return pruneCmd
+}
+
+func pruneImages(cmd *cobra.Command, _ []string) error {
+	if keep < 0 {
+		return fmt.Errorf("--keep can't be negative")
+	}
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	ref, eng, err := connect(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	custom, err := image.CustomizationFromConfig(config.Config)
+	if err != nil {
+		return err
+	}
+	current := imagesInUse(ctx, eng, ref, custom)
+
+	repositories := []string{ref.Name()}
+	if current.keepCustom {
+		log.Warnf("Keeping every customized image, as the one in use couldn't be resolved")
+	} else {
+		repositories = append(repositories, image.CustomRepository)
+	}
+	var tags []string
+	for _, repository := range repositories {
+		images, err := eng.ListImages(ctx, repository)
+		if err != nil {
+			return fmt.Errorf("failed to list images: %v", err)
+		}
+		tags = append(tags, pruneTags(images, keep, current)...)
+	}
+
+	var failed bool
+	for _, tag := range tags {
+		if err := eng.RemoveImage(ctx, tag); err != nil {
+			log.Warnf("Unable to remove %v: %v", tag, err)
+			failed = true
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %v\n", tag)
+	}
+	if failed {
+		return fmt.Errorf("some images couldn't be removed")
+	}
+	return nil
+}
+
+// inUse identifies the images occ run uses with the current config, which are never pruned
+type inUse struct {
+	tags []string
+	// ids are matched against both the digest and the ID of images
+	ids []string
+	// keepCustom keeps every customized image, when the customization in use couldn't be resolved
+	keepCustom bool
+}
+
+func (u inUse) matches(img engine.Image) bool {
+	for _, tag := range u.tags {
+		if hasTag(img, tag) {
+			return true
+		}
+	}
+	for _, id := range u.ids {
+		if trimAlgorithm(id) == trimAlgorithm(img.ID) || (img.Digest != "" && id == img.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// imagesInUse resolves the configured image, by tag and digest, and the customization built on top of it
+func imagesInUse(ctx context.Context, eng engine.Engine, ref image.Ref, custom image.Customization) inUse {
+	var u inUse
+	if ref.Tag != "" {
+		u.tags = append(u.tags, ref.Name()+":"+ref.Tag)
+	}
+	if ref.Digest != "" {
+		u.ids = append(u.ids, ref.Digest)
+	}
+
+	digest, err := eng.ImageDigest(ctx, ref.String())
+	if err != nil {
+		// Without the image there's nothing in use to keep, customizations included
+		log.Debugf("Unable to resolve the digest of %v: %v", ref, err)
+		return u
+	}
+	u.ids = append(u.ids, digest)
+
+	if !custom.Empty() {
+		customRef, _, err := custom.Ref(ref.String(), digest)
+		if err != nil {
+			log.Debugf("Unable to resolve the customization of %v: %v", ref, err)
+			u.keepCustom = true
+			return u
+		}
+		u.tags = append(u.tags, customRef)
+	}
+	return u
+}
+
+func trimAlgorithm(id string) string {
+	return strings.TrimPrefix(id, "sha256:")
+}
+
+// pruneTags returns the tags to remove so only the newest images and the images in use are left.
+// Images are removed by tag, as removing an image with several tags by ID needs force.
+func pruneTags(images []engine.Image, keep int, current inUse) []string {
+	sortNewestFirst(images)
+
+	var tags []string
+	for i, img := range images {
+		if i < keep || current.matches(img) {
+			continue
+		}
+		tags = append(tags, img.Tags...)
+	}
+	return tags
+}
+
+func hasTag(img engine.Image, tag string) bool {
+	for _, t := range img.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
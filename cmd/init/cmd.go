@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/openshift/occ/pkg/config"
-	log "github.com/sirupsen/logrus"
+	"github.com/openshift/occ/pkg/secret"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -20,66 +22,107 @@ This is mounted in the "/root/sop_utils" directory in the container.`
 	WaitingForUserInput = `: `
 )
 
+var secretStore string
+
 func NewInitCmd() *cobra.Command {
 	var initCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initializes OCM container configuration",
 		Long: `init will create a config file at ~/.config/occ/config.yaml.
 If a config.yaml file already exists, you will be asked if you want to overwrite it or exit out.`,
-		Run: setupConfig,
+		RunE: setupConfig,
 	}
+
+	initCmd.Flags().StringVar(&secretStore, "secret-store", "", "Where to keep the offline access token, one of keyring, file or config (defaults to secret_store from the config, or keyring)")
 	return initCmd
 }
 
-func setupConfig(*cobra.Command, []string) {
+func setupConfig(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
 	reader := bufio.NewReader(os.Stdin)
 
 	configPath := config.Config.ConfigFileUsed()
+
+	// Write to the config file alone, so the active profile and defaults don't end up in it
+	v, err := config.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the existing config file: %v", err)
+	}
 	if _, err := os.Stat(configPath); err == nil {
 		if value := prompt(fmt.Sprintf("A config file already exists at %v, would you like to overwrite it? [y/N]", configPath), reader); strings.EqualFold(value, "y") {
 			fmt.Println("The configuration file will be overwritten.")
 			fmt.Println()
 		} else {
-			return
+			return nil
 		}
 	}
 
 	ocmUser := prompt(OCMUsernamePrompt, reader)
-	config.Config.Set(config.OCMUserKey, ocmUser)
+	v.Set(config.OCMUserKey, ocmUser)
 	fmt.Println()
 
 	offlineAccessToken := prompt(OfflineAccessTokenPrompt, reader)
-	config.Config.Set(config.OfflineAccessTokenKey, offlineAccessToken)
+	if err := storeOfflineAccessToken(v, offlineAccessToken); err != nil {
+		return err
+	}
 	fmt.Println()
 
 	opsUtilsDir := prompt(OpsUtilsDirPrompt, reader)
-	config.Config.Set(config.OpsUtilsDirKey, opsUtilsDir)
+	v.Set(config.OpsUtilsDirKey, opsUtilsDir)
 
 	if opsUtilsDir != "" {
 		fmt.Println()
 		if value := prompt(OpsUtilsDirRwPrompt, reader); strings.EqualFold(value, "n") {
-			config.Config.Set(config.OpsUtilsDirRWKey, true)
+			v.Set(config.OpsUtilsDirRWKey, true)
 		} else {
-			config.Config.Set(config.OpsUtilsDirRWKey, false)
+			v.Set(config.OpsUtilsDirRWKey, false)
 		}
 	}
 
-	_, err := os.Stat(config.DefaultConfigFileLocation)
-	if err != nil {
-		err := os.MkdirAll(config.DefaultConfigFileLocation, 0755)
+	configDir := filepath.Dir(configPath)
+	if _, err := os.Stat(configDir); err != nil {
+		err := os.MkdirAll(configDir, 0755)
 		if err != nil {
-			log.Trace(err)
-			log.Fatal("Failed to create necessary path for config file.")
+			return fmt.Errorf("failed to create necessary path for config file: %v", err)
 		}
 	}
 
-	err = config.Config.WriteConfig()
-	if err != nil {
-		log.Trace(err)
-		log.Fatal("Writing the config failed")
+	if err := v.WriteConfig(); err != nil {
+		return fmt.Errorf("writing the config failed: %v", err)
 	}
 
 	fmt.Printf("Config file has been written to %v", configPath)
+	return nil
+}
+
+// storeOfflineAccessToken keeps the token in the selected secret store, and only in
+// the config file itself when the config store was asked for
+func storeOfflineAccessToken(v *viper.Viper, token string) error {
+	storeName := secretStore
+	if storeName == "" {
+		storeName = config.SecretStoreName(config.Config)
+	}
+	v.Set(config.SecretStoreKey, storeName)
+
+	if storeName == secret.Config {
+		v.Set(config.OfflineAccessTokenKey, token)
+		return nil
+	}
+	// Make sure a token from before doesn't linger in plain text, even when no new one is stored
+	v.Set(config.OfflineAccessTokenKey, "")
+	if token == "" {
+		return nil
+	}
+
+	store, err := config.NewSecretStore(v)
+	if err != nil {
+		return err
+	}
+	if err := store.Set(config.SecretKey(config.OfflineAccessTokenKey, config.Profile), token); err != nil {
+		return fmt.Errorf("failed to store the offline access token in the %v secret store: %v. Use --secret-store file on hosts without a keyring", storeName, err)
+	}
+	return nil
 }
 
 type reader interface {
@@ -4,6 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"testing"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/secret"
+	"github.com/spf13/viper"
 )
 
 func TestPrompt(t *testing.T) {
@@ -27,3 +31,21 @@ func TestPrompt(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreOfflineAccessTokenClearsPlainText(t *testing.T) {
+	defer func(store string) { secretStore = store }(secretStore)
+	secretStore = secret.Keyring
+	v := viper.New()
+	v.Set(config.OfflineAccessTokenKey, "oldToken")
+
+	// No token is entered, so the keyring isn't touched
+	if err := storeOfflineAccessToken(v, ""); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if token := v.GetString(config.OfflineAccessTokenKey); token != "" {
+		t.Fatalf("Expected the plain text token to be cleared, got %q", token)
+	}
+	if store := v.GetString(config.SecretStoreKey); store != secret.Keyring {
+		t.Fatalf("Expected the %v store to be selected, got %q", secret.Keyring, store)
+	}
+}
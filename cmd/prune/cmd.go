@@ -0,0 +1,129 @@
+package prune
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/session"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// tempDirPrefix starts the name of every temp dir occ creates
+	tempDirPrefix = "occ_"
+
+	// tempDirMinAge keeps prune away from temp dirs an occ running right now may still be using
+	tempDirMinAge = time.Hour
+)
+
+var (
+	all     bool
+	timeout uint
+)
+
+func NewPruneCmd() *cobra.Command {
+	var pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Removes what crashed occ runs left behind",
+		Long: `prune removes occ session containers that aren't running, such as ones left behind when occ crashed
+before starting them, along with their volumes and secrets. It also removes the secrets of sessions that no
+longer exist, and occ temp dirs older than an hour. Running sessions are only stopped and removed with --all.`,
+		Args: cobra.NoArgs,
+		RunE: prune,
+	}
+
+	pruneCmd.Flags().BoolVarP(&all, "all", "a", false, "Also stop and remove running sessions")
+	pruneCmd.Flags().UintVarP(&timeout, "time", "t", 10, "Seconds to wait for running sessions to stop before killing them, with --all")
+
+	return pruneCmd
+}
+
+func prune(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+	out := cmd.OutOrStdout()
+
+	// Sessions go first, as their containers may still use temp dirs. Temp dirs are on this host,
+	// so they're pruned even when the engine can't be reached.
+	err := pruneSessions(context.Background(), out)
+	pruneTempDirs(out, os.TempDir(), time.Now())
+	return err
+}
+
+// pruneSessions removes the stale session containers, or all of them with --all, and the secrets left behind
+func pruneSessions(ctx context.Context, out io.Writer) error {
+	eng, err := engine.NewFromConfig(ctx, config.Config)
+	if err != nil {
+		return exitcode.New(exitcode.RuntimeConnection, fmt.Errorf("error building connection to the container engine: %v", err))
+	}
+
+	sessions, err := session.List(ctx, eng)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+	if !all {
+		for _, s := range sessions {
+			if s.State == "running" {
+				log.Infof("Leaving running session %v alone, use occ stop %v or occ prune --all to remove it", s.Name, s.Name)
+			}
+		}
+		sessions = session.Stale(sessions, time.Now())
+	}
+	for _, s := range sessions {
+		if err := session.Stop(ctx, eng, s, timeout); err != nil {
+			return fmt.Errorf("failed to prune session %v: %v", s.Name, err)
+		}
+		fmt.Fprintf(out, "container %v\n", s.Name)
+	}
+
+	secrets, err := session.PruneSecrets(ctx, eng)
+	for _, name := range secrets {
+		fmt.Fprintf(out, "secret %v\n", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune secrets: %v", err)
+	}
+	return nil
+}
+
+// pruneTempDirs removes the occ temp dirs in dir that are older than tempDirMinAge.
+// One that can't be removed doesn't stop the others from being pruned.
+func pruneTempDirs(out io.Writer, dir string, now time.Time) {
+	for _, path := range staleTempDirs(dir, now) {
+		if err := os.RemoveAll(path); err != nil {
+			log.Warnf("Unable to remove temp dir %v: %v", path, err)
+			continue
+		}
+		fmt.Fprintf(out, "tempdir %v\n", path)
+	}
+}
+
+func staleTempDirs(dir string, now time.Time) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Debugf("Unable to list temp dirs in %v: %v", dir, err)
+		return nil
+	}
+
+	var stale []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), tempDirPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		// The temp dir may be shared with other users, whose occ runs are none of prune's business
+		if err != nil || !ownedByUser(info) || now.Sub(info.ModTime()) < tempDirMinAge {
+			continue
+		}
+		stale = append(stale, filepath.Join(dir, entry.Name()))
+	}
+	return stale
+}
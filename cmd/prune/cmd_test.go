@@ -0,0 +1,42 @@
+package prune
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPruneTempDirs(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"occ_portmaps123": 2 * time.Hour,
+		"occ_build456":    time.Minute,
+		"other_tool":      2 * time.Hour,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.Mkdir(path, 0700); err != nil {
+			t.Fatalf("Failed to create test dir: %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("Failed to age test dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "occ_file"), nil, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var out bytes.Buffer
+	pruneTempDirs(&out, dir, now)
+	if strings.TrimSpace(out.String()) != "tempdir "+filepath.Join(dir, "occ_portmaps123") {
+		t.Fatalf("Expected only the old occ temp dir to be pruned, got %q", out.String())
+	}
+	for _, name := range []string{"occ_build456", "other_tool", "occ_file"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Expected %v to be kept: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package prune
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// otherUserInfo is a file owned by another user
+type otherUserInfo struct{ fs.FileInfo }
+
+func (otherUserInfo) Sys() interface{} { return &syscall.Stat_t{Uid: uint32(os.Getuid() + 1)} }
+
+func TestOwnedByUser(t *testing.T) {
+	info, err := os.Stat(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to stat test dir: %v", err)
+	}
+	if !ownedByUser(info) {
+		t.Fatalf("Expected the test dir to be owned by the current user")
+	}
+	if ownedByUser(otherUserInfo{info}) {
+		t.Fatalf("Expected a dir of another user not to be owned by the current user")
+	}
+}
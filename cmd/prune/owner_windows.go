@@ -0,0 +1,8 @@
+package prune
+
+import "io/fs"
+
+// ownedByUser reports whether the current user owns the file, which every file in a user's temp dir is on Windows
+func ownedByUser(fs.FileInfo) bool {
+	return true
+}
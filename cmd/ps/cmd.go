@@ -0,0 +1,52 @@
+package ps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/session"
+	"github.com/spf13/cobra"
+)
+
+func NewPsCmd() *cobra.Command {
+	var psCmd = &cobra.Command{
+		Use:   "ps",
+		Short: "Lists occ sessions",
+		Long:  `ps lists the occ sessions known to podman along with the cluster each one was started for.`,
+		Args:  cobra.NoArgs,
+		RunE:  listSessions,
+	}
+	return psCmd
+}
+
+func listSessions(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	eng, err := engine.NewFromConfig(ctx, config.Config)
+	if err != nil {
+		return exitcode.New(exitcode.RuntimeConnection, fmt.Errorf("error building connection to the container engine: %v", err))
+	}
+
+	sessions, err := session.List(ctx, eng)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+
+	return printSessions(cmd.OutOrStdout(), sessions, time.Now())
+}
+
+func printSessions(out io.Writer, sessions []session.Session, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCLUSTER\tSTATE\tCREATED\tIMAGE")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v ago\t%v\n", s.Name, s.ClusterID, s.State, now.Sub(s.Created).Round(time.Second), s.Image)
+	}
+	return w.Flush()
+}
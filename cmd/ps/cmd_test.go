@@ -0,0 +1,37 @@
+package ps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/occ/pkg/session"
+)
+
+func TestPrintSessions(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	sessions := []session.Session{
+		{Name: "occ-abc", ClusterID: "abc", State: "running", Created: now.Add(-90 * time.Second), Image: "localhost/ocm-container:latest"},
+		{Name: "occ-1234abcd", State: "running", Created: now.Add(-time.Hour), Image: "localhost/ocm-container:latest"},
+	}
+
+	out := &bytes.Buffer{}
+	if err := printSessions(out, sessions, now); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 sessions, got:\n%v", out.String())
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "NAME CLUSTER STATE CREATED IMAGE" {
+		t.Fatalf("Unexpected header: %v", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "occ-abc abc running 1m30s ago localhost/ocm-container:latest" {
+		t.Fatalf("Unexpected session line: %v", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "occ-1234abcd running 1h0m0s ago localhost/ocm-container:latest" {
+		t.Fatalf("Unexpected session line: %v", lines[2])
+	}
+}
@@ -0,0 +1,68 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/recording"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// resetTerminal leaves the alternate screen, resets colors and shows the cursor
+const resetTerminal = "\x1b[?1049l\x1b[0m\x1b[?25h"
+
+var speed float64
+
+func NewReplayCmd() *cobra.Command {
+	var replayCmd = &cobra.Command{
+		Use:   "replay <session>",
+		Short: "Plays back a recorded occ session",
+		Long: `replay plays back the recording of an occ session in the terminal, with the timing it was recorded with.
+The session can be given by its name or the cluster ID it was started for, which plays its latest recording,
+by its session ID, or by the path of a recording. Sessions are recorded by occ run --record, or the record config.`,
+		Args: cobra.ExactArgs(1),
+		RunE: replaySession,
+	}
+
+	replayCmd.Flags().Float64VarP(&speed, "speed", "s", 1, "Playback speed, such as 2 for twice as fast or 0.5 for half speed")
+
+	return replayCmd
+}
+
+func replaySession(cmd *cobra.Command, args []string) error {
+	if speed <= 0 {
+		return fmt.Errorf("invalid --speed %v, expected a number greater than 0", speed)
+	}
+	cmd.SilenceUsage = true
+
+	path, err := recording.Find(recording.DirFromConfig(config.Config, config.DefaultDataLocation), args[0])
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the recording: %v", err)
+	}
+	defer f.Close()
+	log.Debugf("Playing %v", path)
+
+	// Stop playing on ctrl-c, rather than leave the terminal in whatever state the recording left it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out := cmd.OutOrStdout()
+	if err := recording.Play(ctx, f, out, speed); err != nil {
+		if errors.Is(err, context.Canceled) {
+			// The recording may have stopped in a full screen program, with the cursor hidden or colors set
+			fmt.Fprint(out, resetTerminal)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
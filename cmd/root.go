@@ -17,8 +17,17 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/openshift/occ/cmd/attach"
+	"github.com/openshift/occ/cmd/audit"
+	"github.com/openshift/occ/cmd/doctor"
+	execCmd "github.com/openshift/occ/cmd/exec"
+	"github.com/openshift/occ/cmd/image"
 	initCmd "github.com/openshift/occ/cmd/init"
+	"github.com/openshift/occ/cmd/prune"
+	"github.com/openshift/occ/cmd/ps"
+	"github.com/openshift/occ/cmd/replay"
 	"github.com/openshift/occ/cmd/run"
+	"github.com/openshift/occ/cmd/stop"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -40,6 +49,9 @@ var (
 
 	// The verbosity level for logs
 	verbosity string
+
+	// The profile to layer over the config file
+	profile string
 )
 
 // NewRootCmd creates an instance of a new rootCmd for bootstrapping the application
@@ -48,8 +60,13 @@ func NewRootCmd() *cobra.Command {
 		Use:   "occ",
 		Short: "OpenShift Command Center - A container-based workflow for SRE-ing OpenShift",
 		Long:  `OpenShift Command Center - This application contains the configuration manipulation and container runtime launcher for managing OpenShift clusters`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			config.InitConfig(cmd, cfgFile)
+		// Errors are logged by main so container exit codes can be passed through without a message
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.InitConfig(cmd, cfgFile, profile); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
 
 			_ = logcfg.ToggleDebug(verbosity, cmd.Flags().Changed("verbosity"))
 
@@ -58,8 +75,12 @@ func NewRootCmd() *cobra.Command {
 			if config.Config.ConfigFileUsed() != "" {
 				log.Debug("Config read in from: ", config.Config.ConfigFileUsed())
 			}
+			if config.Profile != "" {
+				log.Debug("Using profile: ", config.Profile)
+			}
 
 			checkForUpdates(cmd, config.Config)
+			return nil
 		},
 		// Uncomment the following line if your bare application
 		// has an action associated with it:
@@ -79,7 +100,23 @@ func NewRootCmd() *cobra.Command {
 	// Defines the logging verbosity level.  Default is set to 'warn'.
 	rootCmd.PersistentFlags().StringVarP(&verbosity, "verbosity", "v", "warn", "Log Level")
 
-	rootCmd.AddCommand(extension.NewVersionCobraCmd(), initCmd.NewInitCmd(), run.NewRunCmd())
+	// Selects a profile to layer over the config file, also settable with OCC_PROFILE
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to use, overrides default_profile (env OCC_PROFILE)")
+
+	rootCmd.AddCommand(
+		extension.NewVersionCobraCmd(),
+		initCmd.NewInitCmd(),
+		run.NewRunCmd(),
+		ps.NewPsCmd(),
+		attach.NewAttachCmd(),
+		execCmd.NewExecCmd(),
+		stop.NewStopCmd(),
+		doctor.NewDoctorCmd(),
+		image.NewImageCmd(),
+		prune.NewPruneCmd(),
+		audit.NewAuditCmd(),
+		replay.NewReplayCmd(),
+	)
 
 	return rootCmd
 }
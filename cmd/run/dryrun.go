@@ -0,0 +1,114 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/launcher"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputYAML = "yaml"
+	outputJSON = "json"
+)
+
+// dryRunSpec is the printable form of the container spec a session would be created with
+type dryRunSpec struct {
+	Engine              string            `json:"engine" yaml:"engine"`
+	Name                string            `json:"name" yaml:"name"`
+	Image               string            `json:"image" yaml:"image"`
+	CustomImage         string            `json:"customImage,omitempty" yaml:"customImage,omitempty"`
+	Command             []string          `json:"command,omitempty" yaml:"command,omitempty"`
+	Labels              map[string]string `json:"labels" yaml:"labels"`
+	Env                 map[string]string `json:"env" yaml:"env"`
+	Secrets             map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Mounts              []dryRunMount     `json:"mounts" yaml:"mounts"`
+	Copies              []dryRunMount     `json:"copies,omitempty" yaml:"copies,omitempty"`
+	Stdin               bool              `json:"stdin" yaml:"stdin"`
+	Terminal            bool              `json:"terminal" yaml:"terminal"`
+	Remove              bool              `json:"remove" yaml:"remove"`
+	Privileged          bool              `json:"privileged" yaml:"privileged"`
+	PublishExposedPorts bool              `json:"publishExposedPorts" yaml:"publishExposedPorts"`
+	Ports               *dryRunPorts      `json:"ports,omitempty" yaml:"ports,omitempty"`
+}
+
+// dryRunPorts describes the published ports. The engine only picks their host ports on create.
+type dryRunPorts struct {
+	Console  string `json:"console" yaml:"console"`
+	HostPort string `json:"hostPort" yaml:"hostPort"`
+}
+
+type dryRunMount struct {
+	Type        string   `json:"type" yaml:"type"`
+	Source      string   `json:"source,omitempty" yaml:"source,omitempty"`
+	Destination string   `json:"destination" yaml:"destination"`
+	Options     []string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+func newDryRunSpec(engineName string, dryRun launcher.DryRun) dryRunSpec {
+	if engineName == "" {
+		engineName = engine.Podman
+	}
+	spec := dryRun.Spec
+	d := dryRunSpec{
+		Engine:              engineName,
+		Name:                spec.Name,
+		Image:               spec.Image,
+		CustomImage:         dryRun.CustomImage,
+		Command:             spec.Command,
+		Labels:              spec.Labels,
+		Env:                 spec.Env,
+		Secrets:             spec.SecretEnv,
+		Mounts:              newDryRunMounts(spec.Mounts),
+		Copies:              newDryRunMounts(dryRun.Copies),
+		Stdin:               spec.Stdin,
+		Terminal:            spec.Terminal,
+		Remove:              spec.Remove,
+		Privileged:          spec.Privileged,
+		PublishExposedPorts: spec.PublishExposedPorts,
+	}
+	if spec.PublishExposedPorts {
+		d.Ports = &dryRunPorts{
+			Console:  launcher.ConsolePort,
+			HostPort: fmt.Sprintf("picked by the engine on create, and written to %v/portmap in the session", launcher.PortmapDir),
+		}
+	}
+	return d
+}
+
+func newDryRunMounts(mounts []specs.Mount) []dryRunMount {
+	d := []dryRunMount{}
+	for _, m := range mounts {
+		d = append(d, dryRunMount{
+			Type:        m.Type,
+			Source:      m.Source,
+			Destination: m.Destination,
+			Options:     m.Options,
+		})
+	}
+	return d
+}
+
+// printSpec writes what the session would be created with to out in the given format
+func printSpec(out io.Writer, engineName string, dryRun launcher.DryRun, format string) error {
+	d := newDryRunSpec(engineName, dryRun)
+	switch format {
+	case outputYAML:
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+		return enc.Close()
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return fmt.Errorf("unknown output format %q, expected %v or %v", format, outputYAML, outputJSON)
+	}
+}
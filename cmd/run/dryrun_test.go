@@ -0,0 +1,123 @@
+package run
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/launcher"
+)
+
+func testDryRun() launcher.DryRun {
+	return launcher.DryRun{Spec: engine.Spec{
+		Name:   "occ-1234",
+		Image:  "localhost/ocm-container:latest",
+		Labels: map[string]string{"io.openshift.occ.managed": "true"},
+		Env:    map[string]string{"USER": "testUser"},
+		Mounts: []specs.Mount{
+			{Destination: "/root/.ssh/sockets", Type: define.TypeTmpfs},
+			{Source: "/home/test/.ssh", Destination: "/root/.ssh", Options: []string{"ro"}, Type: define.TypeBind},
+		},
+		Stdin:               true,
+		Terminal:            true,
+		Remove:              true,
+		Privileged:          true,
+		PublishExposedPorts: true,
+	}}
+}
+
+func TestPrintSpecYAML(t *testing.T) {
+	var out bytes.Buffer
+	if err := printSpec(&out, "", testDryRun(), outputYAML); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	expected := `engine: podman
+name: occ-1234
+image: localhost/ocm-container:latest
+labels:
+  io.openshift.occ.managed: "true"
+env:
+  USER: testUser
+mounts:
+  - type: tmpfs
+    destination: /root/.ssh/sockets
+  - type: bind
+    source: /home/test/.ssh
+    destination: /root/.ssh
+    options:
+      - ro
+stdin: true
+terminal: true
+remove: true
+privileged: true
+publishExposedPorts: true
+ports:
+  console: 9999/tcp
+  hostPort: picked by the engine on create, and written to /tmp/portmap in the session
+`
+	if out.String() != expected {
+		t.Fatalf("Expected:\n%v\ngot:\n%v", expected, out.String())
+	}
+}
+
+func TestPrintSpecJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := printSpec(&out, engine.Docker, testDryRun(), outputJSON); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	var result dryRunSpec
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected valid json but got %v", err)
+	}
+	if result.Engine != engine.Docker || result.Image != "localhost/ocm-container:latest" || len(result.Mounts) != 2 {
+		t.Fatalf("Unexpected spec %+v", result)
+	}
+	if result.Mounts[1].Source != "/home/test/.ssh" || !result.Privileged {
+		t.Fatalf("Unexpected spec %+v", result)
+	}
+}
+
+func TestPrintSpecWithoutPorts(t *testing.T) {
+	d := testDryRun()
+	d.Spec.PublishExposedPorts = false
+	var out bytes.Buffer
+	if err := printSpec(&out, "", d, outputYAML); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if strings.Contains(out.String(), "ports:") || !strings.Contains(out.String(), "publishExposedPorts: false") {
+		t.Fatalf("Expected no published ports, got:\n%v", out.String())
+	}
+}
+
+func TestPrintSpecUnknownFormat(t *testing.T) {
+	err := printSpec(&bytes.Buffer{}, "", testDryRun(), "toml")
+	if err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Fatalf("Expected an unknown output format error, got %v", err)
+	}
+}
+
+func TestPrintSpecSecrets(t *testing.T) {
+	d := testDryRun()
+	d.Spec.SecretEnv = map[string]string{"OFFLINE_ACCESS_TOKEN": "occ-1234-offline-access-token"}
+	d.CustomImage = "localhost/ocm-container-custom:0123456789ab"
+	d.Copies = []specs.Mount{{Source: "/home/test/.ssh", Destination: "/root/.ssh", Type: define.TypeBind}}
+	var out bytes.Buffer
+	if err := printSpec(&out, "", d, outputYAML); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	for _, expected := range []string{
+		"customImage: localhost/ocm-container-custom:0123456789ab\n",
+		"secrets:\n  OFFLINE_ACCESS_TOKEN: occ-1234-offline-access-token\n",
+		"copies:\n  - type: bind\n    source: /home/test/.ssh\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("Expected the output to contain %q, got:\n%v", expected, out.String())
+		}
+	}
+}
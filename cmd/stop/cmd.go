@@ -0,0 +1,56 @@
+package stop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/exitcode"
+	"github.com/openshift/occ/pkg/session"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	timeout uint
+)
+
+func NewStopCmd() *cobra.Command {
+	var stopCmd = &cobra.Command{
+		Use:   "stop <session>...",
+		Short: "Stops one or more occ sessions",
+		Long: `stop ends the given occ sessions and removes their containers, along with any data left inside them.
+Sessions can be given by their name or by the cluster ID they were started for.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: stopSessions,
+	}
+
+	stopCmd.Flags().UintVarP(&timeout, "time", "t", 10, "Seconds to wait for the session to stop before killing it")
+
+	return stopCmd
+}
+
+func stopSessions(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	eng, err := engine.NewFromConfig(ctx, config.Config)
+	if err != nil {
+		return exitcode.New(exitcode.RuntimeConnection, fmt.Errorf("error building connection to the container engine: %v", err))
+	}
+
+	for _, nameOrCluster := range args {
+		s, err := session.Find(ctx, eng, nameOrCluster)
+		if err != nil {
+			return err
+		}
+
+		log.Debug("Stopping session ", s.Name)
+		if err := session.Stop(ctx, eng, s, timeout); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), s.Name)
+	}
+	return nil
+}
@@ -18,13 +18,23 @@ package main
 import (
 	"os"
 
+	log "github.com/sirupsen/logrus"
+
 	"github.com/openshift/occ/cmd"
+	"github.com/openshift/occ/pkg/exitcode"
 )
 
 func main() {
+	// Anything that still calls log.Fatal is a failure of occ itself, so make sure
+	// it can't be mistaken for the exit code of the container
+	log.StandardLogger().ExitFunc = func(int) {
+		os.Exit(exitcode.Failure)
+	}
+
 	rootCmd := cmd.NewRootCmd()
 	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if err != nil && err.Error() != "" {
+		log.Error(err)
 	}
+	os.Exit(exitcode.FromError(err))
 }
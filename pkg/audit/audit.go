@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// FileKey is the config key of the audit log, which defaults to DefaultFile in the config directory
+	FileKey = "audit_file"
+
+	// DefaultFile is the name of the audit log in the config directory
+	DefaultFile = "audit.jsonl"
+
+	// EventStart is recorded once a session's container is about to start
+	EventStart = "start"
+	// EventEnd is recorded once the session exits, or the user detaches from it
+	EventEnd = "end"
+)
+
+// Record is one line of the audit log. Every session gets a start record, then an end record
+// carrying the same fields along with how it ended. User is the local user who ran occ, Recording
+// is the path of the session's recording, if it was recorded, and Error is set when the session
+// failed to start or occ lost track of it.
+type Record struct {
+	Event       string     `json:"event"`
+	SessionID   string     `json:"session_id"`
+	SessionName string     `json:"session_name"`
+	ClusterID   string     `json:"cluster_id,omitempty"`
+	OCMUser     string     `json:"ocm_user,omitempty"`
+	User        string     `json:"user,omitempty"`
+	Profile     string     `json:"profile,omitempty"`
+	Image       string     `json:"image"`
+	ImageDigest string     `json:"image_digest,omitempty"`
+	Exec        string     `json:"exec,omitempty"`
+	Recording   string     `json:"recording,omitempty"`
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end,omitempty"`
+	ExitCode    *int       `json:"exit_code,omitempty"`
+	Detached    bool       `json:"detached,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// FileFromConfig returns the path of the audit log, which defaults to DefaultFile in configDir
+func FileFromConfig(v *viper.Viper, configDir string) string {
+	path := v.GetString(FileKey)
+	if path == "" {
+		return filepath.Join(configDir, DefaultFile)
+	}
+	return config.ExpandHome(path)
+}
+
+// CurrentUser is the name of the local user, recorded as Record.User
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Append writes the record to the end of the audit log at path. The log is only ever opened
+// for appending, and only its owner can read it.
+func Append(path string, r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create the audit log directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit log: %v", err)
+	}
+	defer f.Close()
+
+	// The mode only applies to new files, so tighten an existing log someone loosened
+	if info, err := f.Stat(); err == nil && info.Mode().Perm()&0077 != 0 {
+		if err := f.Chmod(0600); err != nil {
+			return fmt.Errorf("failed to restrict the audit log permissions: %v", err)
+		}
+	}
+
+	// One write per record, so concurrent sessions can't interleave their lines
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the audit log: %v", err)
+	}
+	return f.Close()
+}
+
+// Filter selects sessions from the audit log. Empty fields match every session.
+type Filter struct {
+	ClusterID string
+	// User matches either the OCM user or the local user
+	User  string
+	Since time.Time
+	Until time.Time
+}
+
+func (f Filter) matches(r Record) bool {
+	if f.ClusterID != "" && r.ClusterID != f.ClusterID {
+		return false
+	}
+	if f.User != "" && r.OCMUser != f.User && r.User != f.User {
+		return false
+	}
+	if !f.Since.IsZero() && r.Start.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.Start.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Sessions reads the audit log and returns one record per session matching the filter, oldest first.
+// Sessions that haven't ended yet, or whose occ crashed, only have their start record.
+func Sessions(r io.Reader, filter Filter) ([]Record, error) {
+	sessions := map[string]Record{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid audit record on line %d: %v", line, err)
+		}
+		if existing, ok := sessions[record.SessionID]; ok && existing.Event == EventEnd && record.Event == EventStart {
+			continue
+		}
+		sessions[record.SessionID] = record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %v", err)
+	}
+
+	var matching []Record
+	for _, record := range sessions {
+		if filter.matches(record) {
+			matching = append(matching, record)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].Start.Before(matching[j].Start) })
+	return matching, nil
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "occ", DefaultFile)
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if err := Append(path, Record{Event: EventStart, SessionID: "abc", Start: start}); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatalf("Failed to loosen permissions: %v", err)
+	}
+	if err := Append(path, Record{Event: EventEnd, SessionID: "abc", Start: start}); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat the audit log: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the audit log to only be readable by its owner, got %v", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"event":"start"`) || !strings.Contains(lines[1], `"event":"end"`) {
+		t.Fatalf("Expected a start and an end record, got %v", lines)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	log := `{"event":"start","session_id":"a","cluster_id":"c1","ocm_user":"alice","user":"al","start":"2024-05-01T09:00:00Z"}
+{"event":"start","session_id":"b","cluster_id":"c2","ocm_user":"bob","start":"2024-05-02T09:00:00Z"}
+{"event":"end","session_id":"a","cluster_id":"c1","ocm_user":"alice","user":"al","start":"2024-05-01T09:00:00Z","end":"2024-05-01T10:00:00Z","exit_code":0}
+
+{"event":"start","session_id":"c","cluster_id":"c1","ocm_user":"bob","start":"2024-05-03T09:00:00Z"}
+`
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{name: "all", expected: []string{"a", "b", "c"}},
+		{name: "cluster", filter: Filter{ClusterID: "c1"}, expected: []string{"a", "c"}},
+		{name: "ocm user", filter: Filter{User: "bob"}, expected: []string{"b", "c"}},
+		{name: "local user", filter: Filter{User: "al"}, expected: []string{"a"}},
+		{name: "date range", filter: Filter{Since: at("2024-05-02T00:00:00Z"), Until: at("2024-05-03T00:00:00Z")}, expected: []string{"b"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions, err := Sessions(strings.NewReader(log), tc.filter)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			var ids []string
+			for _, s := range sessions {
+				ids = append(ids, s.SessionID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("Expected sessions %v, got %v", tc.expected, ids)
+			}
+			for _, s := range sessions {
+				if s.SessionID == "a" && (s.Event != EventEnd || s.ExitCode == nil) {
+					t.Fatalf("Expected the end record of a finished session, got %+v", s)
+				}
+			}
+		})
+	}
+
+	if _, err := Sessions(strings.NewReader("not json\n"), Filter{}); err == nil {
+		t.Fatalf("Expected an error for an invalid record")
+	}
+}
+
+func TestFileFromConfig(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	v := viper.New()
+	if path := FileFromConfig(v, "/home/me/.config/occ"); path != "/home/me/.config/occ/audit.jsonl" {
+		t.Fatalf("Expected the default audit log, got %v", path)
+	}
+	v.Set(FileKey, "~/audit/occ.jsonl")
+	if path := FileFromConfig(v, "/home/me/.config/occ"); path != filepath.Join(home, "audit/occ.jsonl") {
+		t.Fatalf("Expected the configured audit log, got %v", path)
+	}
+}
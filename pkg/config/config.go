@@ -1,9 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"runtime"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -24,6 +25,9 @@ var (
 
 	// DefaultConfigFileLocation is an exported value to use for help docs around the CLI utility
 	DefaultConfigFileLocation string
+
+	// DefaultDataLocation is where occ keeps the data it generates, such as session recordings
+	DefaultDataLocation string
 )
 
 const (
@@ -32,6 +36,10 @@ const (
 	OfflineAccessTokenKey = "offline_access_token"
 	OpsUtilsDirKey        = "ops_utils_dir"
 	OpsUtilsDirRWKey      = "ops_utils_dir_rw"
+	MountsKey             = "mounts"
+	EnvKey                = "env"
+	EnvPassthroughKey     = "env_passthrough"
+	ProvidersKey          = "providers"
 )
 
 func init() {
@@ -43,10 +51,27 @@ func init() {
 	// Look here for default config file. Can be overridden by end-user via flag
 	configPath := fmt.Sprintf("%s/.config/occ", homeDir)
 	DefaultConfigFileLocation = configPath
+	DefaultDataLocation = fmt.Sprintf("%s/.local/share/occ", homeDir)
+}
+
+// ExpandHome expands a leading ~ in a path from the config to the user's home directory
+func ExpandHome(path string) string {
+	return ExpandHomeDir(path, homeDir)
 }
 
-// InitConfig reads in config file and ENV variables if set.
-func InitConfig(cmd *cobra.Command, cfgFile string) {
+// ExpandHomeDir expands a leading ~ in a path to homeDir
+func ExpandHomeDir(path string, homeDir string) string {
+	if path == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
+
+// InitConfig reads in config file and ENV variables if set, and layers the selected profile over them.
+func InitConfig(cmd *cobra.Command, cfgFile string, profile string) error {
 	v := viper.New()
 	if cfgFile != "" {
 		// Use config file from the flag.
@@ -69,38 +94,40 @@ func InitConfig(cmd *cobra.Command, cfgFile string) {
 	v.SetEnvPrefix(envPrefix)
 	v.AutomaticEnv()
 
+	// Apply the profile before binding flags, so it can set their values too
+	Profile = selectProfile(v, profile)
+	if Profile != "" {
+		if err := applyProfile(v, Profile); err != nil {
+			return err
+		}
+	}
+
 	// bind any cobra flags into viper for a single source of truth
 	bindFlags(cmd, v)
 
 	Config = v
+	return nil
+}
+
+// ReadFile returns the contents of the config file alone, without defaults, environment
+// variables or profiles, for commands that write the file back
+func ReadFile(path string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return v, nil
 }
 
 // sets the defaults for any configuration needed that may not be explicitly defined as a flag
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("release-endpoint", "https://api.github.com/repos/iamkirkbater/ocm-container-v2/releases/latest")
 	v.SetDefault("disable-update-checks", false)
-	v.SetDefault("container-image-tag", "latest")
-
-	// Set Defaults for various platforms
-	setLinuxDefaults(v)
-	setMacDefaults(v)
-}
-
-func setLinuxDefaults(v *viper.Viper) {
-	if runtime.GOOS != "linux" {
-		return
-	}
-
-	v.SetDefault("podman-socket", "unix://run/podman/podman.sock")
-}
-
-func setMacDefaults(v *viper.Viper) {
-	if runtime.GOOS != "darwin" {
-		return
-	}
+	v.SetDefault("container-engine", "podman")
 
-	// Assumes podman machine default. could potentially change this in the future.
-	v.SetDefault("podman-socket", fmt.Sprintf("unix://%s/.local/share/containers/podman/machine/podman-machine-default/podman.sock", homeDir))
+	// podman-socket is deliberately left without a default, so the engine can look for the socket
 }
 
 // Bind each cobra flag to its associated viper configuration (config file and environment variable)
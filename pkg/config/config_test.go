@@ -0,0 +1,21 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandHomeDir(t *testing.T) {
+	home := filepath.Join("/home", "user")
+	for path, expected := range map[string]string{
+		"~":                    home,
+		"~/.aws/credentials":   filepath.Join(home, ".aws", "credentials"),
+		"/etc/occ/policy.json": "/etc/occ/policy.json",
+		"relative/~/path":      "relative/~/path",
+		"~other/path":          "~other/path",
+	} {
+		if got := ExpandHomeDir(path, home); got != expected {
+			t.Errorf("Expected %v to expand to %v, got %v", path, expected, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProfileKey selects the profile to layer over the base config, set by --profile or OCC_PROFILE
+	ProfileKey = "profile"
+
+	// DefaultProfileKey is the profile used when none is selected
+	DefaultProfileKey = "default_profile"
+
+	// ProfilesKey holds the profiles defined inline in the base config
+	ProfilesKey = "profiles"
+
+	// profilesDir is where profile files live, next to the base config
+	profilesDir = "profiles"
+)
+
+var (
+	// Profile is the name of the active profile, empty when the base config is used as is
+	Profile string
+
+	// ErrProfileNotFound is returned when the selected profile isn't defined anywhere
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+// selectProfile returns the profile to use, preferring an explicit selection over the default
+func selectProfile(v *viper.Viper, selected string) string {
+	if selected == "" {
+		selected = v.GetString(ProfileKey)
+	}
+	if selected == "" {
+		selected = v.GetString(DefaultProfileKey)
+	}
+	return strings.ToLower(selected)
+}
+
+// applyProfile layers the named profile over the config. The profile can be defined under
+// profiles in the base config, in profiles/<name>.yaml next to it, or both, in which case
+// the file wins. Lists, such as mounts, are replaced rather than appended to.
+func applyProfile(v *viper.Viper, name string) error {
+	found := false
+
+	if inline := v.GetStringMap(ProfilesKey + "." + name); v.IsSet(ProfilesKey + "." + name) {
+		found = true
+		if err := v.MergeConfigMap(inline); err != nil {
+			return fmt.Errorf("failed to apply profile %v: %v", name, err)
+		}
+	}
+
+	fromFile, err := readProfileFile(ProfilePath(v, name))
+	if err != nil {
+		return fmt.Errorf("failed to read profile %v: %v", name, err)
+	}
+	if fromFile != nil {
+		found = true
+		if err := v.MergeConfigMap(fromFile); err != nil {
+			return fmt.Errorf("failed to apply profile %v: %v", name, err)
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("%w: %v is neither under %v in %v nor in %v", ErrProfileNotFound, name, ProfilesKey, v.ConfigFileUsed(), ProfilePath(v, name))
+	}
+	return nil
+}
+
+// ProfilePath returns where the file for the named profile lives
+func ProfilePath(v *viper.Viper, name string) string {
+	dir := DefaultConfigFileLocation
+	if used := v.ConfigFileUsed(); used != "" {
+		dir = filepath.Dir(used)
+	}
+	return filepath.Join(dir, profilesDir, name+".yaml")
+}
+
+// readProfileFile returns the contents of a profile file, or nil if it doesn't exist
+func readProfileFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile := map[string]any{}
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testBaseConfig = `ocm_url: https://api.openshift.com
+offline_access_token: prod-token
+default_profile: staging
+mounts:
+  - source: ~/scripts
+    destination: /root/scripts
+profiles:
+  staging:
+    ocm_url: https://api.stage.openshift.com
+    offline_access_token: stage-token
+  integration:
+    ocm_url: https://api.integration.openshift.com
+`
+
+const testProfileFile = `offline_access_token: int-token
+tag: candidate
+mounts:
+  - source: ~/int-notes
+    destination: /root/notes
+`
+
+func testConfig(t *testing.T) *viper.Viper {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(testBaseConfig), 0600); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, profilesDir), 0700); err != nil {
+		t.Fatalf("Failed to create profiles dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, profilesDir, "integration.yaml"), []byte(testProfileFile), 0600); err != nil {
+		t.Fatalf("Failed to write test profile: %v", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read test config: %v", err)
+	}
+	return v
+}
+
+func TestSelectProfile(t *testing.T) {
+	v := testConfig(t)
+	if result := selectProfile(v, ""); result != "staging" {
+		t.Fatalf("Expected the default profile, got %v", result)
+	}
+	if result := selectProfile(v, "Integration"); result != "integration" {
+		t.Fatalf("Expected the selected profile, got %v", result)
+	}
+
+	t.Setenv("OCC_PROFILE", "integration")
+	v.SetEnvPrefix(envPrefix)
+	v.AutomaticEnv()
+	if result := selectProfile(v, ""); result != "integration" {
+		t.Fatalf("Expected the profile from OCC_PROFILE, got %v", result)
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	type test struct {
+		name          string
+		profile       string
+		expectedURL   string
+		expectedToken string
+		expectedTag   string
+		expectedMount string
+	}
+
+	tests := []test{
+		{
+			name:          "inline profile",
+			profile:       "staging",
+			expectedURL:   "https://api.stage.openshift.com",
+			expectedToken: "stage-token",
+			expectedMount: "/root/scripts",
+		},
+		{
+			name:          "inline profile and profile file",
+			profile:       "integration",
+			expectedURL:   "https://api.integration.openshift.com",
+			expectedToken: "int-token",
+			expectedTag:   "candidate",
+			expectedMount: "/root/notes",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := testConfig(t)
+			if err := applyProfile(v, tc.profile); err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if result := v.GetString(OCMUrlKey); result != tc.expectedURL {
+				t.Errorf("Expected OCM URL %v, got %v", tc.expectedURL, result)
+			}
+			if result := v.GetString(OfflineAccessTokenKey); result != tc.expectedToken {
+				t.Errorf("Expected token %v, got %v", tc.expectedToken, result)
+			}
+			if result := v.GetString("tag"); result != tc.expectedTag {
+				t.Errorf("Expected tag %v, got %v", tc.expectedTag, result)
+			}
+
+			var mounts []map[string]string
+			if err := v.UnmarshalKey(MountsKey, &mounts); err != nil {
+				t.Fatalf("Failed to read mounts: %v", err)
+			}
+			if len(mounts) != 1 || mounts[0]["destination"] != tc.expectedMount {
+				t.Errorf("Expected only the %v mount, got %v", tc.expectedMount, mounts)
+			}
+		})
+	}
+}
+
+func TestApplyProfileNotFound(t *testing.T) {
+	err := applyProfile(testConfig(t), "missing")
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("Expected ErrProfileNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/openshift/occ/pkg/secret"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// SecretStoreKey selects where secrets are kept, one of keyring, file or config
+	SecretStoreKey = "secret_store"
+
+	// SecretsFileKey is the path of the encrypted secrets file, used by the file store
+	SecretsFileKey = "secrets_file"
+
+	// TokenCommandKey is a shell command that prints the offline access token, such as a password manager CLI
+	TokenCommandKey = "token_command"
+)
+
+// SecretStoreName returns the configured secret store, defaulting to the keyring
+func SecretStoreName(v *viper.Viper) string {
+	if name := v.GetString(SecretStoreKey); name != "" {
+		return name
+	}
+	return secret.Keyring
+}
+
+// NewSecretStore returns the configured secret store. The config store has no
+// Store, secrets are read from and written to the config file directly.
+func NewSecretStore(v *viper.Viper) (secret.Store, error) {
+	name := SecretStoreName(v)
+	if name == secret.Config {
+		return nil, nil
+	}
+
+	path := v.GetString(SecretsFileKey)
+	if path == "" {
+		dir := DefaultConfigFileLocation
+		if used := v.ConfigFileUsed(); used != "" {
+			dir = filepath.Dir(used)
+		}
+		path = filepath.Join(dir, "secrets.age")
+	}
+	return secret.New(name, path)
+}
+
+// SecretKey returns the key a secret is stored under for the given profile
+func SecretKey(name string, profile string) string {
+	if profile == "" {
+		return name
+	}
+	return name + "/" + profile
+}
+
+// OfflineAccessToken returns a function that resolves the offline access token, so secret stores
+// are only unlocked when the token is needed. It's taken from, in order: token_command,
+// offline_access_token in the config or environment, then the secret store, looking up the
+// active profile's token before the shared one.
+func OfflineAccessToken(v *viper.Viper, profile string) func() (string, error) {
+	return func() (string, error) {
+		if command := v.GetString(TokenCommandKey); command != "" {
+			return secret.FromCommand(context.Background(), command)
+		}
+		if token := v.GetString(OfflineAccessTokenKey); token != "" {
+			return token, nil
+		}
+
+		store, err := NewSecretStore(v)
+		if err != nil || store == nil {
+			return "", err
+		}
+
+		keys := []string{SecretKey(OfflineAccessTokenKey, profile)}
+		if profile != "" {
+			keys = append(keys, OfflineAccessTokenKey)
+		}
+		for _, key := range keys {
+			token, err := store.Get(key)
+			if err == nil {
+				return token, nil
+			}
+			if errors.Is(err, secret.ErrNotFound) {
+				continue
+			}
+			// Hosts without a keyring that never opted into it shouldn't fail to launch
+			if !v.IsSet(SecretStoreKey) {
+				log.Warnf("Unable to read the offline access token from the keyring: %v", err)
+				return "", nil
+			}
+			return "", fmt.Errorf("unable to read the offline access token from the %v secret store: %v", SecretStoreName(v), err)
+		}
+		return "", nil
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/openshift/occ/pkg/secret"
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+)
+
+func TestOfflineAccessToken(t *testing.T) {
+	keyring.MockInit()
+	store, err := secret.New(secret.Keyring, "")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	if err := store.Set(OfflineAccessTokenKey, "stored-token"); err != nil {
+		t.Fatalf("Failed to store test token: %v", err)
+	}
+	if err := store.Set(SecretKey(OfflineAccessTokenKey, "staging"), "staging-token"); err != nil {
+		t.Fatalf("Failed to store test token: %v", err)
+	}
+
+	type test struct {
+		name     string
+		settings map[string]any
+		profile  string
+		expected string
+	}
+
+	tests := []test{
+		{
+			name:     "token command wins",
+			settings: map[string]any{TokenCommandKey: "echo command-token", OfflineAccessTokenKey: "config-token"},
+			expected: "command-token",
+		},
+		{
+			name:     "plain text token",
+			settings: map[string]any{OfflineAccessTokenKey: "config-token"},
+			expected: "config-token",
+		},
+		{
+			name:     "secret store",
+			expected: "stored-token",
+		},
+		{
+			name:     "profile token",
+			profile:  "staging",
+			expected: "staging-token",
+		},
+		{
+			name:     "profile falls back to the shared token",
+			profile:  "integration",
+			expected: "stored-token",
+		},
+		{
+			name:     "config store without a token",
+			settings: map[string]any{SecretStoreKey: secret.Config},
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := viper.New()
+			for k, val := range tc.settings {
+				v.Set(k, val)
+			}
+			token, err := OfflineAccessToken(v, tc.profile)()
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if token != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, token)
+			}
+		})
+	}
+}
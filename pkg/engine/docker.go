@@ -0,0 +1,578 @@
+package engine
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containers/image/v5/docker/reference"
+	imageconfig "github.com/containers/image/v5/pkg/docker/config"
+	imagetypes "github.com/containers/image/v5/types"
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+type dockerEngine struct {
+	client *client.Client
+
+	// waiters hold the wait channels registered before a container was started,
+	// so the exit code is still available if the container removes itself
+	mu      sync.Mutex
+	waiters map[string]dockerWaiter
+}
+
+type dockerWaiter struct {
+	status <-chan container.ContainerWaitOKBody
+	err    <-chan error
+	// cancel ends the wait request, once the exit code is read, occ detaches or the container is removed
+	cancel context.CancelFunc
+}
+
+// NewDocker connects to the Docker Engine API at the given URI.
+// Without a URI, DOCKER_HOST and the other Docker client environment variables are used.
+func NewDocker(socket string) (Engine, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if socket != "" {
+		opts = append(opts, client.WithHost(socket))
+	}
+	c, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerEngine{client: c, waiters: map[string]dockerWaiter{}}, nil
+}
+
+func (d *dockerEngine) Host() string { return d.client.DaemonHost() }
+
+func (d *dockerEngine) Create(ctx context.Context, spec Spec) (string, error) {
+	config, hostConfig := dockerConfig(spec)
+	resp, err := d.client.ContainerCreate(ctx, config, hostConfig, nil, nil, spec.Name)
+	if err != nil {
+		return "", err
+	}
+	for _, warning := range resp.Warnings {
+		log.Warn(warning)
+	}
+	return resp.ID, nil
+}
+
+func dockerConfig(spec Spec) (*container.Config, *container.HostConfig) {
+	var env []string
+	for k, v := range spec.Env {
+		env = append(env, k+"="+v)
+	}
+
+	config := &container.Config{
+		Image:        spec.Image,
+		Labels:       spec.Labels,
+		Env:          env,
+		Cmd:          spec.Command,
+		Tty:          spec.Terminal,
+		OpenStdin:    spec.Stdin,
+		AttachStdin:  spec.Stdin,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+
+	hostConfig := &container.HostConfig{
+		AutoRemove:      spec.Remove,
+		Privileged:      spec.Privileged,
+		PublishAllPorts: spec.PublishExposedPorts,
+	}
+	for _, m := range spec.Mounts {
+		dockerMount := mount.Mount{
+			Source: m.Source,
+			Target: m.Destination,
+		}
+		switch m.Type {
+		case define.TypeTmpfs:
+			dockerMount.Type = mount.TypeTmpfs
+		default:
+			dockerMount.Type = mount.TypeBind
+		}
+		for _, option := range m.Options {
+			if option == "ro" {
+				dockerMount.ReadOnly = true
+			}
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, dockerMount)
+	}
+	return config, hostConfig
+}
+
+func (d *dockerEngine) Start(ctx context.Context, nameOrID string) error {
+	// Register for the exit code before starting, an auto-removed container may be gone by the time Wait is called
+	condition := container.WaitConditionNextExit
+	if c, err := d.client.ContainerInspect(ctx, nameOrID); err == nil && c.HostConfig != nil && c.HostConfig.AutoRemove {
+		condition = container.WaitConditionRemoved
+	}
+	// The wait ends once the container exits, or it's cancelled when occ is done with the container
+	waitCtx, cancel := context.WithCancel(context.Background())
+	status, waitErr := d.client.ContainerWait(waitCtx, nameOrID, condition)
+	d.mu.Lock()
+	d.waiters[nameOrID] = dockerWaiter{status: status, err: waitErr, cancel: cancel}
+	d.mu.Unlock()
+
+	if err := d.client.ContainerStart(ctx, nameOrID, types.ContainerStartOptions{}); err != nil {
+		d.releaseWaiter(nameOrID)
+		return err
+	}
+	return nil
+}
+
+// releaseWaiter ends the wait registered for the container on start, if any
+func (d *dockerEngine) releaseWaiter(nameOrID string) {
+	d.mu.Lock()
+	waiter, ok := d.waiters[nameOrID]
+	delete(d.waiters, nameOrID)
+	d.mu.Unlock()
+	if ok {
+		waiter.cancel()
+	}
+}
+
+func (d *dockerEngine) Attach(ctx context.Context, nameOrID string, streams Streams, ready chan bool) error {
+	c, err := d.client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return err
+	}
+	tty := c.Config != nil && c.Config.Tty
+
+	resp, err := d.client.ContainerAttach(ctx, nameOrID, types.ContainerAttachOptions{
+		Stream:     true,
+		Stdin:      streams.Stdin != nil,
+		Stdout:     true,
+		Stderr:     true,
+		DetachKeys: DetachKeys,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	if ready != nil {
+		ready <- true
+	}
+
+	if f, ok := stdinFile(streams.Stdin); ok && tty {
+		resizeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		watchResize(resizeCtx, f, func(width uint, height uint) error {
+			return d.client.ContainerResize(ctx, nameOrID, types.ResizeOptions{Width: width, Height: height})
+		})
+	}
+
+	if err := d.stream(ctx, resp, streams, tty); err != nil {
+		return err
+	}
+
+	// The daemon handles the detach keys by closing the stream, which looks just like the container exiting
+	if c, err := d.client.ContainerInspect(ctx, nameOrID); err == nil && c.State != nil && c.State.Running && streams.Stdin != nil {
+		// Nobody waits for a session that was detached from
+		d.releaseWaiter(nameOrID)
+		return ErrDetached
+	}
+	return nil
+}
+
+// stream copies the streams over a hijacked connection until the container's output ends
+func (d *dockerEngine) stream(ctx context.Context, resp types.HijackedResponse, streams Streams, tty bool) error {
+	if f, ok := stdinFile(streams.Stdin); ok && tty && term.IsTerminal(int(f.Fd())) {
+		restore, err := makeRaw(f)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	streams = streams.teed()
+
+	outputDone := make(chan error, 1)
+	go func() {
+		var err error
+		if tty {
+			_, err = io.Copy(streams.Stdout, resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(streams.Stdout, streams.Stderr, resp.Reader)
+		}
+		outputDone <- err
+	}()
+
+	if streams.Stdin != nil {
+		go func() {
+			if _, err := io.Copy(resp.Conn, streams.Stdin); err != nil {
+				log.Debugf("Failed to write input to container: %v", err)
+			}
+			if err := resp.CloseWrite(); err != nil {
+				log.Debugf("Failed to close container input: %v", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-outputDone:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *dockerEngine) Inspect(ctx context.Context, nameOrID string) (*Container, error) {
+	data, err := d.client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Container{
+		ID:    data.ID,
+		Name:  strings.TrimPrefix(data.Name, "/"),
+		Ports: map[string][]string{},
+	}
+	if created, err := time.Parse(time.RFC3339Nano, data.Created); err == nil {
+		c.Created = created
+	}
+	if data.State != nil {
+		c.State = data.State.Status
+		c.Running = data.State.Running
+	}
+	if data.Config != nil {
+		c.Image = data.Config.Image
+		c.Labels = data.Config.Labels
+	}
+	if data.NetworkSettings != nil {
+		for port, bindings := range data.NetworkSettings.Ports {
+			for _, binding := range bindings {
+				c.Ports[string(port)] = append(c.Ports[string(port)], binding.HostPort)
+			}
+		}
+	}
+	return c, nil
+}
+
+func (d *dockerEngine) Exists(ctx context.Context, nameOrID string) (bool, error) {
+	_, err := d.client.ContainerInspect(ctx, nameOrID)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *dockerEngine) List(ctx context.Context, labels map[string]string) ([]Container, error) {
+	args := filters.NewArgs()
+	for k, v := range labels {
+		args.Add("label", k+"="+v)
+	}
+	ctrs, err := d.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Container, 0, len(ctrs))
+	for _, ctr := range ctrs {
+		c := Container{
+			ID:      ctr.ID,
+			Image:   ctr.Image,
+			State:   ctr.State,
+			Running: ctr.State == "running",
+			Created: time.Unix(ctr.Created, 0),
+			Labels:  ctr.Labels,
+		}
+		if len(ctr.Names) > 0 {
+			c.Name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (d *dockerEngine) CopyToContainer(ctx context.Context, nameOrID string, path string, reader io.Reader) error {
+	return d.client.CopyToContainer(ctx, nameOrID, path, reader, types.CopyToContainerOptions{})
+}
+
+func (d *dockerEngine) Wait(ctx context.Context, nameOrID string) (int, error) {
+	d.mu.Lock()
+	waiter, ok := d.waiters[nameOrID]
+	d.mu.Unlock()
+	if ok {
+		defer d.releaseWaiter(nameOrID)
+	} else {
+		status, err := d.client.ContainerWait(ctx, nameOrID, container.WaitConditionNotRunning)
+		waiter = dockerWaiter{status: status, err: err}
+	}
+
+	select {
+	case result := <-waiter.status:
+		if result.Error != nil {
+			return -1, errors.New(result.Error.Message)
+		}
+		return int(result.StatusCode), nil
+	case err := <-waiter.err:
+		return -1, err
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+}
+
+func (d *dockerEngine) Stop(ctx context.Context, nameOrID string, timeout uint) error {
+	t := time.Duration(timeout) * time.Second
+	err := d.client.ContainerStop(ctx, nameOrID, &t)
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (d *dockerEngine) Remove(ctx context.Context, nameOrID string, force bool) error {
+	d.releaseWaiter(nameOrID)
+	err := d.client.ContainerRemove(ctx, nameOrID, types.ContainerRemoveOptions{Force: force, RemoveVolumes: true})
+	if client.IsErrNotFound(err) || (err != nil && strings.Contains(err.Error(), "is already in progress")) {
+		return nil
+	}
+	return err
+}
+
+func (d *dockerEngine) Exec(ctx context.Context, nameOrID string, config ExecConfig, streams Streams) (int, error) {
+	execResp, err := d.client.ContainerExecCreate(ctx, nameOrID, types.ExecConfig{
+		Cmd:          config.Command,
+		Env:          config.Env,
+		Tty:          config.Tty,
+		AttachStdin:  streams.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		DetachKeys:   DetachKeys,
+	})
+	if err != nil {
+		return -1, fmt.Errorf("failed to create exec session: %v", err)
+	}
+
+	resp, err := d.client.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{Tty: config.Tty})
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Close()
+
+	if f, ok := stdinFile(streams.Stdin); ok && config.Tty {
+		resizeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		watchResize(resizeCtx, f, func(width uint, height uint) error {
+			return d.client.ContainerExecResize(ctx, execResp.ID, types.ResizeOptions{Width: width, Height: height})
+		})
+	}
+
+	if err := d.stream(ctx, resp, streams, config.Tty); err != nil {
+		return -1, err
+	}
+
+	inspect, err := d.client.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return -1, fmt.Errorf("failed to retrieve the exec session exit code: %v", err)
+	}
+	return inspect.ExitCode, nil
+}
+
+func (d *dockerEngine) ImageExists(ctx context.Context, ref string) (bool, error) {
+	_, _, err := d.client.ImageInspectWithRaw(ctx, ref)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *dockerEngine) PullImage(ctx context.Context, ref string, authFile string, out io.Writer) error {
+	auth, err := registryAuth(ref, authFile)
+	if err != nil {
+		return err
+	}
+	progress, err := d.client.ImagePull(ctx, ref, types.ImagePullOptions{RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+	if out == nil {
+		out = io.Discard
+	}
+	return jsonmessage.DisplayJSONMessagesStream(progress, out, 0, false, nil)
+}
+
+func (d *dockerEngine) ImageDigest(ctx context.Context, ref string) (string, error) {
+	inspect, _, err := d.client.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	for _, repoDigest := range inspect.RepoDigests {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+			return digest, nil
+		}
+	}
+	return inspect.ID, nil
+}
+
+func (d *dockerEngine) BuildImage(ctx context.Context, options BuildOptions, out io.Writer) (string, error) {
+	if err := options.validate(); err != nil {
+		return "", err
+	}
+	buildContext, err := archive.TarWithOptions(options.ContextDir, &archive.TarOptions{})
+	if err != nil {
+		return "", err
+	}
+	containerfile, err := filepath.Rel(options.ContextDir, options.Containerfile)
+	if err != nil || strings.HasPrefix(containerfile, "..") {
+		// Docker only reads the Containerfile from the build context, so it's added to it
+		data, err := os.ReadFile(options.Containerfile)
+		if err != nil {
+			buildContext.Close()
+			return "", err
+		}
+		containerfile = ".occ.Containerfile"
+		buildContext = addToArchive(buildContext, containerfile, data)
+	}
+	defer buildContext.Close()
+
+	resp, err := d.client.ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Tags:        options.Tags,
+		Dockerfile:  filepath.ToSlash(containerfile),
+		PullParent:  options.PullBase,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		out = io.Discard
+	}
+	if err := jsonmessage.DisplayJSONMessagesStream(resp.Body, out, 0, false, nil); err != nil {
+		return "", err
+	}
+
+	inspect, _, err := d.client.ImageInspectWithRaw(ctx, options.Tags[0])
+	if err != nil {
+		return "", err
+	}
+	return inspect.ID, nil
+}
+
+// addToArchive appends a file to a tar archive
+func addToArchive(archive io.ReadCloser, name string, data []byte) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		defer archive.Close()
+		tw := tar.NewWriter(writer)
+		tr := tar.NewReader(archive)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		if _, err := tw.Write(data); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		writer.CloseWithError(tw.Close())
+	}()
+	return reader
+}
+
+func (d *dockerEngine) ListImages(ctx context.Context, repository string) ([]Image, error) {
+	summaries, err := d.client.ImageList(ctx, types.ImageListOptions{Filters: filters.NewArgs(filters.Arg("reference", repository))})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Image
+	for _, s := range summaries {
+		tags := repositoryTags(s.RepoTags, repository)
+		if len(tags) == 0 {
+			continue
+		}
+		image := Image{ID: s.ID, Tags: tags, Created: time.Unix(s.Created, 0), Size: s.Size}
+		for _, repoDigest := range s.RepoDigests {
+			if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+				image.Digest = digest
+				break
+			}
+		}
+		list = append(list, image)
+	}
+	return list, nil
+}
+
+func (d *dockerEngine) RemoveImage(ctx context.Context, ref string) error {
+	_, err := d.client.ImageRemove(ctx, ref, types.ImageRemoveOptions{PruneChildren: true})
+	return err
+}
+
+// registryAuth encodes the credentials for the registry of ref from the containers auth.json,
+// which also falls back to the Docker config, in the form the Docker Engine API expects
+func registryAuth(ref string, authFile string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", err
+	}
+	creds, err := imageconfig.GetCredentials(&imagetypes.SystemContext{AuthFilePath: authFile}, reference.Domain(named))
+	if err != nil {
+		return "", fmt.Errorf("failed to read registry credentials: %v", err)
+	}
+	if creds == (imagetypes.DockerAuthConfig{}) {
+		return "", nil
+	}
+	data, err := json.Marshal(types.AuthConfig{Username: creds.Username, Password: creds.Password, IdentityToken: creds.IdentityToken})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// CreateSecret isn't supported, Docker Engine only has secrets for swarm services
+func (d *dockerEngine) CreateSecret(context.Context, string, []byte, map[string]string) error {
+	return ErrSecretsUnsupported
+}
+
+func (d *dockerEngine) ListSecrets(context.Context, map[string]string) ([]Secret, error) {
+	return nil, nil
+}
+
+func (d *dockerEngine) RemoveSecret(context.Context, string) error {
+	return nil
+}
+
+// stdinFile returns the reader as a file, if it is one, so raw terminal mode can be set on it
+func stdinFile(r io.Reader) (*os.File, bool) {
+	f, ok := r.(*os.File)
+	return f, ok && f != nil
+}
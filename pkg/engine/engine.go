@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Podman is the default engine, reached through the podman REST API
+	Podman = "podman"
+
+	// Docker is any engine speaking the Docker Engine API
+	Docker = "docker"
+
+	// DetachKeys is the key sequence that detaches from a session without stopping it
+	DetachKeys = "ctrl-p,ctrl-q"
+
+	// EngineKey selects which engine occ launches sessions with
+	EngineKey = "container-engine"
+
+	// PodmanSocketKey is the URI of the podman socket. When unset, occ looks for one.
+	PodmanSocketKey = "podman-socket"
+
+	// DockerSocketKey is the URI of the Docker Engine socket. When unset, DOCKER_HOST and the Docker defaults are used.
+	DockerSocketKey = "docker-socket"
+
+	// PodmanSSHIdentityKey is the private key used for ssh:// podman sockets
+	PodmanSSHIdentityKey = "podman-ssh-identity"
+
+	// PodmanSSHInsecureKey skips host key checks of ssh:// podman sockets
+	PodmanSSHInsecureKey = "podman-ssh-insecure"
+)
+
+var (
+	// ErrDetached is returned by Attach and Exec when the user detached with DetachKeys
+	ErrDetached = errors.New("detached from container")
+
+	// ErrSecretsUnsupported is returned by CreateSecret on engines without secrets outside of a swarm
+	ErrSecretsUnsupported = errors.New("container engine doesn't support secrets")
+)
+
+// Engine is a container runtime occ can launch and manage sessions with
+type Engine interface {
+	// Create creates, but doesn't start, a container from the spec and returns its ID
+	Create(ctx context.Context, spec Spec) (string, error)
+	Start(ctx context.Context, nameOrID string) error
+	// Attach connects the streams to the container until its output ends.
+	// If ready is not nil, it's signalled once the attach is in place.
+	Attach(ctx context.Context, nameOrID string, streams Streams, ready chan bool) error
+	Inspect(ctx context.Context, nameOrID string) (*Container, error)
+	Exists(ctx context.Context, nameOrID string) (bool, error)
+	// List returns all containers, running or not, carrying every one of the given labels
+	List(ctx context.Context, labels map[string]string) ([]Container, error)
+	// CopyToContainer extracts the tar archive read from reader into path in the container
+	CopyToContainer(ctx context.Context, nameOrID string, path string, reader io.Reader) error
+	// Wait blocks until the container exits and returns the exit code of its main process
+	Wait(ctx context.Context, nameOrID string) (int, error)
+	Stop(ctx context.Context, nameOrID string, timeout uint) error
+	// Remove removes the container, ignoring containers that no longer exist
+	Remove(ctx context.Context, nameOrID string, force bool) error
+	// Exec runs a command in a running container and returns its exit code
+	Exec(ctx context.Context, nameOrID string, config ExecConfig, streams Streams) (int, error)
+	// ImageExists reports whether the image is available locally
+	ImageExists(ctx context.Context, ref string) (bool, error)
+	// PullImage pulls the image with the credentials in authFile, or the containers auth.json when empty.
+	// Progress is written to out, if it isn't nil.
+	PullImage(ctx context.Context, ref string, authFile string, out io.Writer) error
+	// ImageDigest returns the digest of the local image, or its ID if it was built locally
+	ImageDigest(ctx context.Context, ref string) (string, error)
+	// BuildImage builds an image, writing the build output to out, and returns its ID
+	BuildImage(ctx context.Context, options BuildOptions, out io.Writer) (string, error)
+	// ListImages returns the local images with a tag in the repository, such as localhost/ocm-container
+	ListImages(ctx context.Context, repository string) ([]Image, error)
+	// RemoveImage removes an image by ID, or just the tag if given a reference to an image with several tags
+	RemoveImage(ctx context.Context, ref string) error
+	// CreateSecret stores data as a secret containers can be created with
+	CreateSecret(ctx context.Context, name string, data []byte, labels map[string]string) error
+	// ListSecrets returns the secrets carrying every one of the given labels
+	ListSecrets(ctx context.Context, labels map[string]string) ([]Secret, error)
+	// RemoveSecret removes the secret, ignoring secrets that no longer exist
+	RemoveSecret(ctx context.Context, name string) error
+	// Host is the URI of the socket the engine is connected to
+	Host() string
+}
+
+// Connection is where to reach an engine
+type Connection struct {
+	// Socket is the URI of the engine. When empty, podman looks for a socket and Docker uses DOCKER_HOST.
+	Socket string
+	// SSHIdentity is the private key for ssh:// podman sockets, on top of the ssh agent
+	SSHIdentity string
+	// SSHInsecure accepts any host key from ssh:// podman sockets. By default unknown hosts
+	// are added to ~/.ssh/known_hosts, and hosts whose key changed are refused.
+	SSHInsecure bool
+}
+
+// Spec describes the container to create, independent of the engine
+type Spec struct {
+	Name   string
+	Image  string
+	Labels map[string]string
+	Env    map[string]string
+	// SecretEnv maps env variables to the engine secret their value is read from
+	SecretEnv  map[string]string
+	Mounts     []specs.Mount
+	Command    []string
+	Stdin      bool
+	Terminal   bool
+	Remove     bool
+	Privileged bool
+	// PublishExposedPorts publishes every port the image exposes on a random host port
+	PublishExposedPorts bool
+}
+
+// Container is the engine independent view of a container
+type Container struct {
+	ID      string
+	Name    string
+	Image   string
+	State   string
+	Running bool
+	Created time.Time
+	Labels  map[string]string
+	// Ports maps a container port, such as 9999/tcp, to the host ports it's published on
+	Ports map[string][]string
+}
+
+// Secret is the engine independent view of a secret, without its value
+type Secret struct {
+	ID      string
+	Name    string
+	Created time.Time
+	Labels  map[string]string
+}
+
+// Image is the engine independent view of a local image
+type Image struct {
+	ID string
+	// Tags are the references to the image in the listed repository, such as localhost/ocm-container:latest
+	Tags []string
+	// Digest is the manifest digest of the image, if it was pulled or pushed
+	Digest  string
+	Created time.Time
+	Size    int64
+}
+
+// BuildOptions describes an image build
+type BuildOptions struct {
+	// ContextDir is sent to the engine as the build context
+	ContextDir string
+	// Containerfile is the path of the Containerfile or Dockerfile
+	Containerfile string
+	// Tags are the references the image is tagged with, there must be at least one
+	Tags []string
+	// PullBase pulls the base image even when it's available locally
+	PullBase bool
+	// AuthFile holds the registry credentials for the base image, defaults to the containers auth.json
+	AuthFile string
+}
+
+// validate checks the options can be built with
+func (o BuildOptions) validate() error {
+	if len(o.Tags) == 0 {
+		return errors.New("no tag to build the image as")
+	}
+	return nil
+}
+
+// repositoryTags returns the tags in the repository
+func repositoryTags(tags []string, repository string) []string {
+	var matching []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, repository+":") {
+			matching = append(matching, tag)
+		}
+	}
+	return matching
+}
+
+// Streams are the host side of a container's standard streams.
+// A nil Stdin means nothing is forwarded to the container.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Tee gets a copy of the output written to Stdout and Stderr, and TeeInput a copy of the input read
+	// from Stdin, when they're set. The terminal is handled the same as without them.
+	Tee      io.Writer
+	TeeInput io.Writer
+}
+
+// teed returns the streams with the output and input copied to Tee and TeeInput
+func (s Streams) teed() Streams {
+	if s.Tee != nil {
+		s.Stdout = io.MultiWriter(s.Stdout, s.Tee)
+		if s.Stderr != nil {
+			s.Stderr = io.MultiWriter(s.Stderr, s.Tee)
+		}
+	}
+	if s.TeeInput != nil && s.Stdin != nil {
+		s.Stdin = io.TeeReader(s.Stdin, s.TeeInput)
+	}
+	s.Tee, s.TeeInput = nil, nil
+	return s
+}
+
+// ExecConfig describes a command to run in an existing container
+type ExecConfig struct {
+	Command []string
+	Env     []string
+	Tty     bool
+}
+
+// New connects to the named engine
+func New(ctx context.Context, name string, conn Connection) (Engine, error) {
+	switch name {
+	case Podman, "":
+		return NewPodman(ctx, conn)
+	case Docker:
+		return NewDocker(conn.Socket)
+	default:
+		return nil, fmt.Errorf("unknown container engine %q, expected %v or %v", name, Podman, Docker)
+	}
+}
+
+// NewFromConfig connects to the engine selected in the given config
+func NewFromConfig(ctx context.Context, v *viper.Viper) (Engine, error) {
+	name, conn := ConnectionFromConfig(v)
+	log.Tracef("Using %v engine at: %v", name, conn.Socket)
+	return New(ctx, name, conn)
+}
+
+// ConnectionFromConfig returns the engine selected in the given config, and how to reach it
+func ConnectionFromConfig(v *viper.Viper) (string, Connection) {
+	name := v.GetString(EngineKey)
+	if name == Docker {
+		return name, Connection{Socket: v.GetString(DockerSocketKey)}
+	}
+
+	conn := Connection{
+		Socket:      v.GetString(PodmanSocketKey),
+		SSHIdentity: v.GetString(PodmanSSHIdentityKey),
+		SSHInsecure: v.GetBool(PodmanSSHInsecureKey),
+	}
+	conn.SSHIdentity = config.ExpandHome(conn.SSHIdentity)
+	return name, conn
+}
+
+// IsRemote reports whether the engine at the socket URI runs on another host,
+// where paths on this host can't be bind mounted
+func IsRemote(socket string) bool {
+	u, err := url.Parse(socket)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "ssh":
+		return true
+	case "tcp", "http", "https":
+		host := u.Hostname()
+		if host == "localhost" {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip == nil || !ip.IsLoopback()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/spf13/viper"
+)
+
+var testSpec = Spec{
+	Name:   "occ-test",
+	Image:  "localhost/ocm-container:latest",
+	Labels: map[string]string{"io.openshift.occ.managed": "true"},
+	Env:    map[string]string{"OCM_URL": "testOcmUrl"},
+	Mounts: []specs.Mount{
+		{Destination: "/root/.ssh/sockets", Type: define.TypeTmpfs},
+		{Source: "home_dir/.ssh", Destination: "/root/.ssh", Options: []string{"ro"}, Type: define.TypeBind},
+		{Source: "testOpsUtilsDir", Destination: "/root/sop-utils", Options: []string{"rw"}, Type: define.TypeBind},
+	},
+	Command:             []string{"/root/check.sh"},
+	Stdin:               true,
+	Terminal:            true,
+	Remove:              true,
+	Privileged:          true,
+	PublishExposedPorts: true,
+}
+
+func TestPodmanSpec(t *testing.T) {
+	s := podmanSpec(testSpec)
+
+	var failures []string
+	if s.Image != testSpec.Image || s.Name != testSpec.Name {
+		failures = append(failures, "image or name was not carried over")
+	}
+	if s.Labels["io.openshift.occ.managed"] != "true" || s.Env["OCM_URL"] != "testOcmUrl" {
+		failures = append(failures, "labels or env were not carried over")
+	}
+	if len(s.Mounts) != len(testSpec.Mounts) || strings.Join(s.Command, " ") != "/root/check.sh" {
+		failures = append(failures, "mounts or command were not carried over")
+	}
+	if !s.Stdin || !s.Terminal || !s.Remove || !s.Privileged || !s.PublishExposedPorts {
+		failures = append(failures, "flags were not carried over")
+	}
+
+	if len(failures) > 0 {
+		t.Fatalf(strings.Join(failures, "\n"))
+	}
+}
+
+func TestDockerConfig(t *testing.T) {
+	config, hostConfig := dockerConfig(testSpec)
+
+	var failures []string
+	if config.Image != testSpec.Image || strings.Join(config.Cmd, " ") != "/root/check.sh" {
+		failures = append(failures, "image or command were not carried over")
+	}
+	if strings.Join(config.Env, ",") != "OCM_URL=testOcmUrl" {
+		failures = append(failures, "env was not converted, got "+strings.Join(config.Env, ","))
+	}
+	if !config.Tty || !config.OpenStdin || !config.AttachStdin {
+		failures = append(failures, "terminal and stdin were not carried over")
+	}
+	if !hostConfig.AutoRemove || !hostConfig.Privileged || !hostConfig.PublishAllPorts {
+		failures = append(failures, "host config flags were not carried over")
+	}
+
+	expectedMounts := []mount.Mount{
+		{Type: mount.TypeTmpfs, Target: "/root/.ssh/sockets"},
+		{Type: mount.TypeBind, Source: "home_dir/.ssh", Target: "/root/.ssh", ReadOnly: true},
+		{Type: mount.TypeBind, Source: "testOpsUtilsDir", Target: "/root/sop-utils", ReadOnly: false},
+	}
+	if len(hostConfig.Mounts) != len(expectedMounts) {
+		t.Fatalf("Expected %v mounts, got %v", len(expectedMounts), len(hostConfig.Mounts))
+	}
+	for i, expected := range expectedMounts {
+		m := hostConfig.Mounts[i]
+		if m.Type != expected.Type || m.Source != expected.Source || m.Target != expected.Target || m.ReadOnly != expected.ReadOnly {
+			failures = append(failures, "unexpected mount for "+expected.Target)
+		}
+	}
+
+	if len(failures) > 0 {
+		t.Fatalf(strings.Join(failures, "\n"))
+	}
+}
+
+func TestNewUnknownEngine(t *testing.T) {
+	_, err := New(context.Background(), "containerd", Connection{})
+	if err == nil || err.Error() != `unknown container engine "containerd", expected podman or docker` {
+		t.Fatalf("Expected an unknown engine error, got %v", err)
+	}
+}
+
+func TestIsRemote(t *testing.T) {
+	tests := map[string]bool{
+		"unix:///run/podman/podman.sock": false,
+		"":                               false,
+		"ssh://core@build-host:22/run/podman/podman.sock": true,
+		"tcp://localhost:8080":                            false,
+		"tcp://127.0.0.1:8080":                            false,
+		"tcp://10.0.0.5:8080":                             true,
+		"tcp://build-host:2375":                           true,
+	}
+	for socket, expected := range tests {
+		if IsRemote(socket) != expected {
+			t.Errorf("Expected IsRemote(%q) to be %v", socket, expected)
+		}
+	}
+}
+
+func TestConnectionFromConfig(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	v := viper.New()
+	v.Set(PodmanSocketKey, "ssh://core@build-host/run/podman/podman.sock")
+	v.Set(PodmanSSHIdentityKey, "~/.ssh/build_host")
+	v.Set(PodmanSSHInsecureKey, true)
+	v.Set(DockerSocketKey, "unix:///var/run/docker.sock")
+
+	name, conn := ConnectionFromConfig(v)
+	expected := Connection{Socket: "ssh://core@build-host/run/podman/podman.sock", SSHIdentity: filepath.Join(home, ".ssh/build_host"), SSHInsecure: true}
+	if name != "" || conn != expected {
+		t.Fatalf("Expected %+v, got %v %+v", expected, name, conn)
+	}
+
+	v.Set(EngineKey, Docker)
+	name, conn = ConnectionFromConfig(v)
+	if name != Docker || conn != (Connection{Socket: "unix:///var/run/docker.sock"}) {
+		t.Fatalf("Expected the docker socket alone, got %v %+v", name, conn)
+	}
+}
+
+type testKey string
+
+func TestConnContext(t *testing.T) {
+	conn := context.WithValue(context.Background(), testKey("Client"), "client")
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), testKey("caller"), "caller"))
+	merged := connContext{Context: ctx, conn: conn}
+
+	if merged.Value(testKey("Client")) != "client" {
+		t.Fatalf("Expected the connection's values to be visible")
+	}
+	if merged.Value(testKey("caller")) != "caller" {
+		t.Fatalf("Expected the caller's values to be visible")
+	}
+
+	cancel()
+	if merged.Err() == nil {
+		t.Fatalf("Expected the caller's cancellation to be honoured")
+	}
+}
+
+func TestAddToArchive(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "vimrc", Mode: 0600, Size: 3}); err != nil {
+		t.Fatalf("Failed to write test archive: %v", err)
+	}
+	tw.Write([]byte("set"))
+	tw.Close()
+
+	archive := addToArchive(io.NopCloser(&buf), ".occ.Containerfile", []byte("FROM base"))
+	defer archive.Close()
+
+	contents := map[string]string{}
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		contents[hdr.Name] = string(data)
+	}
+	if len(contents) != 2 || contents["vimrc"] != "set" || contents[".occ.Containerfile"] != "FROM base" {
+		t.Fatalf("Expected the original file and the Containerfile, got %v", contents)
+	}
+}
+
+func TestForwardResize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan os.Signal)
+	resized := make(chan [2]uint)
+	width := 80
+	size := func() (int, int, error) { return width, 24, nil }
+	done := make(chan bool)
+	go func() {
+		forwardResize(ctx, size, changes, func(w uint, h uint) error {
+			resized <- [2]uint{w, h}
+			return nil
+		})
+		done <- true
+	}()
+
+	if got := <-resized; got != [2]uint{80, 24} {
+		t.Fatalf("Expected the initial size to be forwarded, got %v", got)
+	}
+	width = 120
+	changes <- resizeSignal
+	if got := <-resized; got != [2]uint{120, 24} {
+		t.Fatalf("Expected the new size to be forwarded, got %v", got)
+	}
+	cancel()
+	<-done
+}
+
+func TestTeed(t *testing.T) {
+	var stdout, stderr, tee, teeInput bytes.Buffer
+	streams := Streams{Stdin: strings.NewReader("ls\n"), Stdout: &stdout, Stderr: &stderr, Tee: &tee, TeeInput: &teeInput}.teed()
+	if streams.Tee != nil || streams.TeeInput != nil {
+		t.Fatalf("Expected the tees to be applied, got %+v", streams)
+	}
+
+	input, _ := io.ReadAll(streams.Stdin)
+	fmt.Fprint(streams.Stdout, "out ")
+	fmt.Fprint(streams.Stderr, "err")
+	if string(input) != "ls\n" || stdout.String() != "out " || stderr.String() != "err" {
+		t.Fatalf("Expected the streams to be unchanged, got %q %q %q", input, stdout.String(), stderr.String())
+	}
+	if tee.String() != "out err" || teeInput.String() != "ls\n" {
+		t.Fatalf("Expected a copy of the output and input, got %q %q", tee.String(), teeInput.String())
+	}
+
+	// Without stdin there's no input to copy
+	streams = Streams{Stdout: &stdout, TeeInput: &teeInput}.teed()
+	if streams.Stdin != nil || streams.Stdout != &stdout {
+		t.Fatalf("Expected the streams to be left alone, got %+v", streams)
+	}
+}
+
+func TestBuildImageWithoutTags(t *testing.T) {
+	for _, eng := range []Engine{&podmanEngine{}, &dockerEngine{}} {
+		if _, err := eng.BuildImage(context.Background(), BuildOptions{ContextDir: t.TempDir()}, nil); err == nil {
+			t.Fatalf("Expected %T to refuse to build without tags", eng)
+		}
+	}
+}
+
+// dockerAPI answers the Docker client's requests in process
+type dockerAPI func(*http.Request) *http.Response
+
+func (f dockerAPI) RoundTrip(r *http.Request) (*http.Response, error) { return f(r), nil }
+
+func TestDockerRemoveEndsWait(t *testing.T) {
+	waitEnded := make(chan struct{})
+	api := dockerAPI(func(r *http.Request) *http.Response {
+		status, body := http.StatusNoContent, io.Reader(strings.NewReader(""))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/wait"):
+			// The daemon only sends the exit code once the container exits, which this one never does
+			reader, writer := io.Pipe()
+			go func() {
+				<-r.Context().Done()
+				writer.CloseWithError(r.Context().Err())
+				close(waitEnded)
+			}()
+			status, body = http.StatusOK, reader
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/json"):
+			status, body = http.StatusOK, strings.NewReader(`{"Id": "test-id"}`)
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(body), Request: r}
+	})
+	c, err := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: api}), client.WithVersion("1.41"))
+	if err != nil {
+		t.Fatalf("Failed to create the Docker client: %v", err)
+	}
+
+	eng := &dockerEngine{client: c, waiters: map[string]dockerWaiter{}}
+	if err := eng.Start(context.Background(), "test-id"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err := eng.Remove(context.Background(), "test-id", true); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	select {
+	case <-waitEnded:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected removing the container to end the wait for it")
+	}
+}
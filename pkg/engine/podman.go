@@ -0,0 +1,431 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	buildahDefine "github.com/containers/buildah/define"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/containers/podman/v4/pkg/api/handlers"
+	"github.com/containers/podman/v4/pkg/bindings"
+	"github.com/containers/podman/v4/pkg/bindings/containers"
+	"github.com/containers/podman/v4/pkg/bindings/images"
+	"github.com/containers/podman/v4/pkg/bindings/secrets"
+	"github.com/containers/podman/v4/pkg/bindings/system"
+	"github.com/containers/podman/v4/pkg/domain/entities"
+	"github.com/containers/podman/v4/pkg/errorhandling"
+	"github.com/containers/podman/v4/pkg/specgen"
+	"golang.org/x/term"
+)
+
+type podmanEngine struct {
+	// conn is the context returned by bindings.NewConnection, which carries the client
+	conn context.Context
+	host string
+}
+
+// NewPodman connects to the podman socket at the given URI. Without a URI, it connects to the first socket found.
+func NewPodman(ctx context.Context, conn Connection) (Engine, error) {
+	if conn.Socket == "" {
+		c, candidate, err := discoverPodmanSocket(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		return &podmanEngine{conn: c, host: candidate.URI}, nil
+	}
+
+	c, err := bindings.NewConnectionWithIdentity(ctx, conn.Socket, conn.SSHIdentity, conn.SSHInsecure)
+	if err != nil {
+		return nil, err
+	}
+	return &podmanEngine{conn: c, host: conn.Socket}, nil
+}
+
+// connContext lets the bindings find their client while honouring the caller's cancellation
+type connContext struct {
+	context.Context
+	conn context.Context
+}
+
+func (c connContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.conn.Value(key)
+}
+
+func (p *podmanEngine) Host() string { return p.host }
+
+func (p *podmanEngine) ctx(ctx context.Context) context.Context {
+	return connContext{Context: ctx, conn: p.conn}
+}
+
+func (p *podmanEngine) Create(ctx context.Context, spec Spec) (string, error) {
+	createResponse, err := containers.CreateWithSpec(p.ctx(ctx), podmanSpec(spec), nil)
+	if err != nil {
+		return "", err
+	}
+	return createResponse.ID, nil
+}
+
+func podmanSpec(spec Spec) *specgen.SpecGenerator {
+	s := specgen.NewSpecGenerator(spec.Image, false)
+	s.Name = spec.Name
+	s.Labels = spec.Labels
+	s.Env = spec.Env
+	s.EnvSecrets = spec.SecretEnv
+	s.Mounts = spec.Mounts
+	s.Command = spec.Command
+	s.Stdin = spec.Stdin
+	s.Terminal = spec.Terminal
+	s.Remove = spec.Remove
+	s.Privileged = spec.Privileged
+	s.PublishExposedPorts = spec.PublishExposedPorts
+	return s
+}
+
+func (p *podmanEngine) Start(ctx context.Context, nameOrID string) error {
+	return containers.Start(p.ctx(ctx), nameOrID, nil)
+}
+
+// Attach relies on the bindings to put the terminal in raw mode and forward resizes,
+// which they do when Stdin and Stdout are both terminals and the container has a TTY.
+// Teeing the streams hides the terminals from the bindings, so Attach handles it itself then.
+func (p *podmanEngine) Attach(ctx context.Context, nameOrID string, streams Streams, ready chan bool) error {
+	if streams.Tee != nil || streams.TeeInput != nil {
+		restore, err := p.attachTerminal(ctx, nameOrID, streams)
+		if err != nil {
+			return err
+		}
+		defer restore()
+		streams = streams.teed()
+	}
+
+	options := new(containers.AttachOptions).WithStream(true).WithDetachKeys(DetachKeys)
+	err := containers.Attach(p.ctx(ctx), nameOrID, streams.Stdin, streams.Stdout, streams.Stderr, ready, options)
+	if errors.Is(err, define.ErrDetach) {
+		return ErrDetached
+	}
+	return err
+}
+
+// attachTerminal puts the terminal in raw mode and forwards its resizes to the container, as the
+// bindings would, returning a func that restores the terminal
+func (p *podmanEngine) attachTerminal(ctx context.Context, nameOrID string, streams Streams) (func(), error) {
+	f, ok := stdinFile(streams.Stdin)
+	out, outOk := streams.Stdout.(*os.File)
+	if !ok || !outOk || !term.IsTerminal(int(f.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return func() {}, nil
+	}
+	data, err := containers.Inspect(p.ctx(ctx), nameOrID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if data.Config == nil || !data.Config.Tty {
+		return func() {}, nil
+	}
+
+	restore, err := makeRaw(f)
+	if err != nil {
+		return nil, err
+	}
+	resizeCtx, cancel := context.WithCancel(ctx)
+	watchResize(resizeCtx, out, func(width uint, height uint) error {
+		options := new(containers.ResizeTTYOptions).WithWidth(int(width)).WithHeight(int(height))
+		return containers.ResizeContainerTTY(p.ctx(ctx), nameOrID, options)
+	})
+	return func() {
+		cancel()
+		restore()
+	}, nil
+}
+
+func (p *podmanEngine) Inspect(ctx context.Context, nameOrID string) (*Container, error) {
+	data, err := containers.Inspect(p.ctx(ctx), nameOrID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Container{
+		ID:      data.ID,
+		Name:    data.Name,
+		Image:   data.ImageName,
+		Created: data.Created,
+		Ports:   map[string][]string{},
+	}
+	if data.State != nil {
+		c.State = data.State.Status
+		c.Running = data.State.Running
+	}
+	if data.Config != nil {
+		c.Labels = data.Config.Labels
+	}
+	if data.NetworkSettings != nil {
+		for port, hosts := range data.NetworkSettings.Ports {
+			for _, host := range hosts {
+				c.Ports[port] = append(c.Ports[port], host.HostPort)
+			}
+		}
+	}
+	return c, nil
+}
+
+func (p *podmanEngine) Exists(ctx context.Context, nameOrID string) (bool, error) {
+	return containers.Exists(p.ctx(ctx), nameOrID, nil)
+}
+
+func (p *podmanEngine) List(ctx context.Context, labels map[string]string) ([]Container, error) {
+	var labelFilters []string
+	for k, v := range labels {
+		labelFilters = append(labelFilters, k+"="+v)
+	}
+	options := new(containers.ListOptions).WithAll(true).WithFilters(map[string][]string{
+		"label": labelFilters,
+	})
+	ctrs, err := containers.List(p.ctx(ctx), options)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Container, 0, len(ctrs))
+	for _, ctr := range ctrs {
+		c := Container{
+			ID:      ctr.ID,
+			Image:   ctr.Image,
+			State:   ctr.State,
+			Running: ctr.State == "running",
+			Created: ctr.Created,
+			Labels:  ctr.Labels,
+		}
+		if len(ctr.Names) > 0 {
+			c.Name = ctr.Names[0]
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (p *podmanEngine) CopyToContainer(ctx context.Context, nameOrID string, path string, reader io.Reader) error {
+	copyFunc, err := containers.CopyFromArchive(p.ctx(ctx), nameOrID, path, reader)
+	if err != nil {
+		return err
+	}
+	return copyFunc()
+}
+
+// Wait returns the exit code of the container's main process.
+// Sessions are removed as soon as they stop, so if it's already gone we look for
+// its exit event instead, the same way the podman remote client does.
+func (p *podmanEngine) Wait(ctx context.Context, nameOrID string) (int, error) {
+	code, err := containers.Wait(p.ctx(ctx), nameOrID, nil)
+	if err == nil {
+		return int(code), nil
+	}
+	if !errorhandling.Contains(err, define.ErrNoSuchCtr) {
+		return -1, err
+	}
+
+	eventChan := make(chan entities.Event)
+	eventsErr := make(chan error, 1)
+	options := new(system.EventsOptions).WithStream(false).WithFilters(map[string][]string{
+		"type":      {"container"},
+		"container": {nameOrID},
+		"event":     {"died"},
+	})
+	go func() {
+		eventsErr <- system.Events(p.ctx(ctx), eventChan, nil, options)
+	}()
+
+	exitCode := -1
+	for event := range eventChan {
+		if eventCode, err := strconv.Atoi(event.Actor.Attributes["containerExitCode"]); err == nil {
+			exitCode = eventCode
+		}
+	}
+	if err := <-eventsErr; err != nil {
+		return -1, err
+	}
+	if exitCode == -1 {
+		return -1, fmt.Errorf("no exit event found for container %v", nameOrID)
+	}
+	return exitCode, nil
+}
+
+func (p *podmanEngine) Stop(ctx context.Context, nameOrID string, timeout uint) error {
+	return containers.Stop(p.ctx(ctx), nameOrID, new(containers.StopOptions).WithTimeout(timeout).WithIgnore(true))
+}
+
+func (p *podmanEngine) Remove(ctx context.Context, nameOrID string, force bool) error {
+	_, err := containers.Remove(p.ctx(ctx), nameOrID, new(containers.RemoveOptions).WithForce(force).WithIgnore(true).WithVolumes(true))
+	return err
+}
+
+func (p *podmanEngine) Exec(ctx context.Context, nameOrID string, config ExecConfig, streams Streams) (int, error) {
+	execConfig := &handlers.ExecCreateConfig{}
+	execConfig.Cmd = config.Command
+	execConfig.Env = config.Env
+	execConfig.Tty = config.Tty
+	execConfig.AttachStdin = streams.Stdin != nil
+	execConfig.AttachStdout = true
+	execConfig.AttachStderr = true
+
+	execID, err := containers.ExecCreate(p.ctx(ctx), nameOrID, execConfig)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create exec session: %v", err)
+	}
+
+	streams = streams.teed()
+	options := new(containers.ExecStartAndAttachOptions).
+		WithOutputStream(nopWriteCloser{streams.Stdout}).
+		WithErrorStream(nopWriteCloser{streams.Stderr}).
+		WithAttachOutput(true).
+		WithAttachError(true)
+	if streams.Stdin != nil {
+		options.WithInputStream(*bufio.NewReader(streams.Stdin)).WithAttachInput(true)
+	}
+	if err := containers.ExecStartAndAttach(p.ctx(ctx), execID, options); err != nil {
+		return -1, err
+	}
+
+	inspect, err := containers.ExecInspect(p.ctx(ctx), execID, nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to retrieve the exec session exit code: %v", err)
+	}
+	return inspect.ExitCode, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (p *podmanEngine) ImageExists(ctx context.Context, ref string) (bool, error) {
+	return images.Exists(p.ctx(ctx), ref, nil)
+}
+
+func (p *podmanEngine) PullImage(ctx context.Context, ref string, authFile string, out io.Writer) error {
+	options := new(images.PullOptions)
+	if authFile != "" {
+		options.WithAuthfile(authFile)
+	}
+	if out != nil {
+		options.WithProgressWriter(out)
+	} else {
+		options.WithQuiet(true)
+	}
+	_, err := images.Pull(p.ctx(ctx), ref, options)
+	return err
+}
+
+func (p *podmanEngine) ImageDigest(ctx context.Context, ref string) (string, error) {
+	report, err := images.GetImage(p.ctx(ctx), ref, nil)
+	if err != nil {
+		return "", err
+	}
+	if report.Digest != "" {
+		return report.Digest.String(), nil
+	}
+	return report.ID, nil
+}
+
+func (p *podmanEngine) BuildImage(ctx context.Context, options BuildOptions, out io.Writer) (string, error) {
+	if err := options.validate(); err != nil {
+		return "", err
+	}
+	buildOptions := entities.BuildOptions{BuildOptions: buildahDefine.BuildOptions{
+		ContextDirectory: options.ContextDir,
+		Output:           options.Tags[0],
+		AdditionalTags:   options.Tags[1:],
+		PullPolicy:       buildahDefine.PullIfMissing,
+		SystemContext:    &types.SystemContext{AuthFilePath: options.AuthFile},
+		Out:              out,
+		Err:              out,
+		ReportWriter:     out,
+	}}
+	if options.PullBase {
+		buildOptions.PullPolicy = buildahDefine.PullAlways
+	}
+	report, err := images.Build(p.ctx(ctx), []string{options.Containerfile}, buildOptions)
+	if err != nil {
+		return "", err
+	}
+	return report.ID, nil
+}
+
+func (p *podmanEngine) ListImages(ctx context.Context, repository string) ([]Image, error) {
+	options := new(images.ListOptions).WithFilters(map[string][]string{"reference": {repository}})
+	summaries, err := images.List(p.ctx(ctx), options)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Image
+	for _, s := range summaries {
+		tags := repositoryTags(s.RepoTags, repository)
+		if len(tags) == 0 {
+			continue
+		}
+		list = append(list, Image{ID: s.ID, Tags: tags, Digest: s.Digest, Created: time.Unix(s.Created, 0), Size: s.Size})
+	}
+	return list, nil
+}
+
+func (p *podmanEngine) RemoveImage(ctx context.Context, ref string) error {
+	_, errs := images.Remove(p.ctx(ctx), []string{ref}, nil)
+	return errorhandling.JoinErrors(errs)
+}
+
+func (p *podmanEngine) CreateSecret(ctx context.Context, name string, data []byte, labels map[string]string) error {
+	options := new(secrets.CreateOptions).WithName(name).WithLabels(labels)
+	_, err := secrets.Create(p.ctx(ctx), bytes.NewReader(data), options)
+	return err
+}
+
+func (p *podmanEngine) ListSecrets(ctx context.Context, labels map[string]string) ([]Secret, error) {
+	reports, err := secrets.List(p.ctx(ctx), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Secret
+	for _, report := range reports {
+		// Older podman services can't filter secrets by label, so it's done here
+		if !hasLabels(report.Spec.Labels, labels) {
+			continue
+		}
+		list = append(list, Secret{
+			ID:      report.ID,
+			Name:    report.Spec.Name,
+			Created: report.CreatedAt,
+			Labels:  report.Spec.Labels,
+		})
+	}
+	return list, nil
+}
+
+func (p *podmanEngine) RemoveSecret(ctx context.Context, name string) error {
+	err := secrets.Remove(p.ctx(ctx), name)
+	if err != nil && strings.Contains(err.Error(), "no such secret") {
+		return nil
+	}
+	return err
+}
+
+func hasLabels(labels map[string]string, want map[string]string) bool {
+	for k, v := range want {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// resizeSignal is sent when the terminal is resized
+var resizeSignal os.Signal = syscall.SIGWINCH
@@ -0,0 +1,6 @@
+package engine
+
+import "os"
+
+// resizeSignal is nil as Windows doesn't signal terminal resizes, the TTY is only sized once
+var resizeSignal os.Signal
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	containersconfig "github.com/containers/common/pkg/config"
+	"github.com/containers/podman/v4/pkg/bindings"
+	log "github.com/sirupsen/logrus"
+)
+
+// rootfulPodmanSocket is where a system wide podman service listens
+const rootfulPodmanSocket = "unix:///run/podman/podman.sock"
+
+// socketCandidate is a podman socket occ may connect to, along with where it was found
+type socketCandidate struct {
+	Source   string
+	URI      string
+	Identity string
+}
+
+// podmanSocketCandidates lists the podman sockets to try when none is configured, in order:
+// CONTAINER_HOST, the rootless socket, the rootful socket, the podman machine socket on macOS,
+// then the connections from containers.conf, starting with the default one.
+func podmanSocketCandidates(getenv func(string) string, goos string, homeDir string, engineConfig *containersconfig.EngineConfig) []socketCandidate {
+	var candidates []socketCandidate
+	if host := getenv("CONTAINER_HOST"); host != "" {
+		candidates = append(candidates, socketCandidate{Source: "CONTAINER_HOST", URI: host, Identity: getenv("CONTAINER_SSHKEY")})
+	}
+	if dir := getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, socketCandidate{Source: "rootless socket", URI: "unix://" + filepath.Join(dir, "podman", "podman.sock")})
+	}
+	candidates = append(candidates, socketCandidate{Source: "rootful socket", URI: rootfulPodmanSocket})
+	if goos == "darwin" && homeDir != "" {
+		machineSocket := filepath.Join(homeDir, ".local/share/containers/podman/machine/podman-machine-default/podman.sock")
+		candidates = append(candidates, socketCandidate{Source: "podman machine socket", URI: "unix://" + machineSocket})
+	}
+
+	if engineConfig == nil {
+		return candidates
+	}
+	var names []string
+	for name := range engineConfig.ServiceDestinations {
+		if name != engineConfig.ActiveService {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := engineConfig.ServiceDestinations[engineConfig.ActiveService]; ok {
+		names = append([]string{engineConfig.ActiveService}, names...)
+	}
+	for _, name := range names {
+		d := engineConfig.ServiceDestinations[name]
+		candidates = append(candidates, socketCandidate{Source: fmt.Sprintf("containers.conf connection %v", name), URI: d.URI, Identity: d.Identity})
+	}
+	return candidates
+}
+
+// discoverPodman connects to the first candidate that answers
+func discoverPodman(ctx context.Context, candidates []socketCandidate, connect func(context.Context, socketCandidate) (context.Context, error)) (context.Context, socketCandidate, error) {
+	var failures []string
+	for _, c := range candidates {
+		conn, err := connect(ctx, c)
+		if err != nil {
+			log.Debugf("Not using the %v %v: %v", c.Source, c.URI, err)
+			failures = append(failures, fmt.Sprintf("%v %v: %v", c.Source, c.URI, err))
+			continue
+		}
+		log.Debugf("Using the %v %v", c.Source, c.URI)
+		return conn, c, nil
+	}
+	return nil, socketCandidate{}, fmt.Errorf("no podman socket found, set %v in your config or CONTAINER_HOST (tried %v)", PodmanSocketKey, strings.Join(failures, "; "))
+}
+
+// connectPodman connects to the candidate, checking unix sockets exist first for a clearer error
+func connectPodman(ctx context.Context, c socketCandidate, conn Connection) (context.Context, error) {
+	if u, err := url.Parse(c.URI); err == nil && u.Scheme == "unix" {
+		if _, err := os.Stat(u.Path); err != nil {
+			return nil, err
+		}
+	}
+	identity := c.Identity
+	if identity == "" {
+		identity = conn.SSHIdentity
+	}
+	return bindings.NewConnectionWithIdentity(ctx, c.URI, identity, conn.SSHInsecure)
+}
+
+// discoverPodmanSocket connects to the first podman socket found on this host
+func discoverPodmanSocket(ctx context.Context, conn Connection) (context.Context, socketCandidate, error) {
+	var engineConfig *containersconfig.EngineConfig
+	if c, err := containersconfig.ReadCustomConfig(); err != nil {
+		log.Debugf("Not using the connections in containers.conf: %v", err)
+	} else {
+		engineConfig = &c.Engine
+	}
+	homeDir, _ := os.UserHomeDir()
+
+	connect := func(ctx context.Context, c socketCandidate) (context.Context, error) {
+		return connectPodman(ctx, c, conn)
+	}
+	return discoverPodman(ctx, podmanSocketCandidates(os.Getenv, runtime.GOOS, homeDir, engineConfig), connect)
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	containersconfig "github.com/containers/common/pkg/config"
+)
+
+func TestPodmanSocketCandidates(t *testing.T) {
+	engineConfig := &containersconfig.EngineConfig{
+		ActiveService: "work",
+		ServiceDestinations: map[string]containersconfig.Destination{
+			"b-remote": {URI: "ssh://core@b/run/podman/podman.sock", Identity: "/keys/b"},
+			"a-remote": {URI: "ssh://core@a/run/podman/podman.sock"},
+			"work":     {URI: "ssh://core@work/run/podman/podman.sock"},
+		},
+	}
+
+	type test struct {
+		name         string
+		env          map[string]string
+		goos         string
+		engineConfig *containersconfig.EngineConfig
+		expected     []string
+	}
+
+	tests := []test{
+		{
+			name:     "only the rootful socket",
+			goos:     "linux",
+			expected: []string{rootfulPodmanSocket},
+		},
+		{
+			name:     "CONTAINER_HOST then the rootless socket",
+			env:      map[string]string{"CONTAINER_HOST": "tcp://localhost:8080", "XDG_RUNTIME_DIR": "/run/user/1000"},
+			goos:     "linux",
+			expected: []string{"tcp://localhost:8080", "unix:///run/user/1000/podman/podman.sock", rootfulPodmanSocket},
+		},
+		{
+			name:     "podman machine on macOS",
+			goos:     "darwin",
+			expected: []string{rootfulPodmanSocket, "unix:///home/.local/share/containers/podman/machine/podman-machine-default/podman.sock"},
+		},
+		{
+			name:         "containers.conf connections, default first",
+			goos:         "linux",
+			engineConfig: engineConfig,
+			expected: []string{
+				rootfulPodmanSocket,
+				"ssh://core@work/run/podman/podman.sock",
+				"ssh://core@a/run/podman/podman.sock",
+				"ssh://core@b/run/podman/podman.sock",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			getenv := func(key string) string { return tc.env[key] }
+			candidates := podmanSocketCandidates(getenv, tc.goos, "/home", tc.engineConfig)
+
+			var uris []string
+			for _, c := range candidates {
+				uris = append(uris, c.URI)
+			}
+			if strings.Join(uris, " ") != strings.Join(tc.expected, " ") {
+				t.Fatalf("Expected %v, got %v", tc.expected, uris)
+			}
+		})
+	}
+
+	t.Run("identities are kept", func(t *testing.T) {
+		candidates := podmanSocketCandidates(func(string) string { return "" }, "linux", "/home", engineConfig)
+		if last := candidates[len(candidates)-1]; last.Identity != "/keys/b" {
+			t.Fatalf("Expected the identity of b-remote, got %+v", last)
+		}
+	})
+}
+
+func TestDiscoverPodman(t *testing.T) {
+	candidates := []socketCandidate{
+		{Source: "first", URI: "unix:///missing.sock"},
+		{Source: "second", URI: "unix:///podman.sock"},
+		{Source: "third", URI: "unix:///unused.sock"},
+	}
+	var tried []string
+	connect := func(ctx context.Context, c socketCandidate) (context.Context, error) {
+		tried = append(tried, c.Source)
+		if c.Source == "first" {
+			return nil, errors.New("no such file or directory")
+		}
+		return ctx, nil
+	}
+
+	_, picked, err := discoverPodman(context.Background(), candidates, connect)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if picked.Source != "second" || strings.Join(tried, " ") != "first second" {
+		t.Fatalf("Expected the second candidate to be picked after the first, got %v after trying %v", picked.Source, tried)
+	}
+
+	fail := func(context.Context, socketCandidate) (context.Context, error) { return nil, errors.New("refused") }
+	_, _, err = discoverPodman(context.Background(), candidates, fail)
+	if err == nil || !strings.Contains(err.Error(), "third unix:///unused.sock: refused") {
+		t.Fatalf("Expected every failure in the error, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// watchResize resizes the container's TTY to the size of the terminal, now and whenever the
+// terminal is resized, until ctx is done
+func watchResize(ctx context.Context, f *os.File, resize func(width uint, height uint) error) {
+	changes := make(chan os.Signal, 1)
+	if resizeSignal != nil {
+		signal.Notify(changes, resizeSignal)
+	}
+	size := func() (int, int, error) { return term.GetSize(int(f.Fd())) }
+	go func() {
+		defer signal.Stop(changes)
+		forwardResize(ctx, size, changes, resize)
+	}()
+}
+
+// forwardResize calls resize with the current size, then again on every change until ctx is done
+func forwardResize(ctx context.Context, size func() (int, int, error), changes <-chan os.Signal, resize func(width uint, height uint) error) {
+	for {
+		if width, height, err := size(); err != nil {
+			log.Debugf("Unable to get the terminal size: %v", err)
+		} else if err := resize(uint(width), uint(height)); err != nil {
+			log.Debugf("Unable to resize the container TTY: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+		}
+	}
+}
+
+// makeRaw puts the terminal in raw mode, returning a func that restores it
+func makeRaw(f *os.File) (func(), error) {
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := term.Restore(int(f.Fd()), state); err != nil {
+			log.Errorf("Unable to restore terminal: %v", err)
+		}
+	}, nil
+}
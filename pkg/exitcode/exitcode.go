@@ -0,0 +1,68 @@
+package exitcode
+
+import (
+	"errors"
+)
+
+// occ passes the exit code of the container's process straight through, so the codes
+// below are reserved for failures of occ itself, and scripts run with --exec should avoid them.
+// They sit just under the 126-127 range that shells reserve. Failure reuses 125 on purpose,
+// as podman run and docker run exit with it when they fail themselves rather than the container.
+const (
+	// Success means both occ and the container exited cleanly
+	Success = 0
+
+	// ConfigNotFound means there was no config file at the expected location
+	ConfigNotFound = 120
+
+	// RuntimeConnection means occ could not connect to the container runtime socket
+	RuntimeConnection = 121
+
+	// ContainerCreate means the container runtime refused to create the container
+	ContainerCreate = 122
+
+	// ContainerStart means the container was created but could not be started
+	ContainerStart = 123
+
+	// ContainerAttach means occ could not attach to, or lost, the container's streams
+	ContainerAttach = 124
+
+	// Failure is any other failure of occ itself, including invalid flags and arguments
+	Failure = 125
+)
+
+// Error carries the exit code the occ process should exit with alongside the error that caused it.
+// Err may be nil when the code is simply passed through from the container.
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns an error that makes occ exit with the given code
+func New(code int, err error) error {
+	return &Error{Code: code, Err: err}
+}
+
+// FromError returns the process exit code for the error returned by a command.
+// Errors that don't carry a code are treated as a generic occ failure.
+func FromError(err error) int {
+	if err == nil {
+		return Success
+	}
+
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return Failure
+}
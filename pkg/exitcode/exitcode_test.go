@@ -0,0 +1,40 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	type test struct {
+		name     string
+		err      error
+		expected int
+	}
+
+	tests := []test{
+		{name: "no error", err: nil, expected: Success},
+		{name: "plain error", err: errors.New("fail"), expected: Failure},
+		{name: "reserved code", err: New(ConfigNotFound, errors.New("fail")), expected: ConfigNotFound},
+		{name: "container exit code", err: New(3, nil), expected: 3},
+		{name: "wrapped code", err: fmt.Errorf("wrapped: %w", New(RuntimeConnection, errors.New("fail"))), expected: RuntimeConnection},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := FromError(tc.err); result != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	if msg := New(3, nil).Error(); msg != "" {
+		t.Fatalf(`Expected an empty message for a passed through exit code, got "%v"`, msg)
+	}
+	if msg := New(Failure, errors.New("fail")).Error(); msg != "fail" {
+		t.Fatalf(`Expected "fail", got "%v"`, msg)
+	}
+}
@@ -0,0 +1,142 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// CustomizeKey is the config section describing the personal layer built on top of the session image
+	CustomizeKey = "customize"
+
+	// CustomRepository is where customized images are built to, tagged by CustomTag
+	CustomRepository = DefaultRegistry + "/" + DefaultRepository + "-custom"
+
+	// BaseLabel records the digest of the image a customized image was built from
+	BaseLabel = "io.openshift.occ.customize.base"
+)
+
+// Customization is a thin personal layer, such as extra tools or dotfiles, built FROM the session image
+type Customization struct {
+	// Packages are installed with dnf
+	Packages []string `mapstructure:"packages"`
+	// Run are shell commands run after the packages are installed
+	Run []string `mapstructure:"run"`
+	// Containerfile is a fragment appended to the generated Containerfile, without a FROM line
+	Containerfile string `mapstructure:"containerfile"`
+	// Context is the build context the fragment can COPY files from. Without it the context is empty.
+	Context string `mapstructure:"context"`
+}
+
+// CustomizationFromConfig reads the customize section of the config
+func CustomizationFromConfig(v *viper.Viper) (Customization, error) {
+	var c Customization
+	if err := v.UnmarshalKey(CustomizeKey, &c); err != nil {
+		return c, fmt.Errorf("invalid %v in config: %v", CustomizeKey, err)
+	}
+	c.Containerfile = config.ExpandHome(c.Containerfile)
+	c.Context = config.ExpandHome(c.Context)
+	return c, nil
+}
+
+// Empty reports whether there's nothing to customize
+func (c Customization) Empty() bool {
+	return len(c.Packages) == 0 && len(c.Run) == 0 && c.Containerfile == ""
+}
+
+// BuildContainerfile generates the Containerfile of the layer on top of base, whose digest is recorded in a label
+func (c Customization) BuildContainerfile(base string, baseDigest string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "FROM %v\n", base)
+	fmt.Fprintf(&b, "LABEL %v=%q\n", BaseLabel, baseDigest)
+	if len(c.Packages) > 0 {
+		fmt.Fprintf(&b, "RUN dnf install -y %v && dnf clean all\n", strings.Join(c.Packages, " "))
+	}
+	for _, run := range c.Run {
+		fmt.Fprintf(&b, "RUN %v\n", run)
+	}
+
+	if c.Containerfile != "" {
+		fragment, err := os.ReadFile(c.Containerfile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the %v Containerfile: %v", CustomizeKey, err)
+		}
+		b.Write(fragment)
+		if len(fragment) > 0 && fragment[len(fragment)-1] != '\n' {
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// Ref returns the reference the customization of base is cached under, along with its Containerfile
+func (c Customization) Ref(base string, baseDigest string) (string, string, error) {
+	containerfile, err := c.BuildContainerfile(base, baseDigest)
+	if err != nil {
+		return "", "", err
+	}
+	tag, err := CustomTag(baseDigest, containerfile, c.Context)
+	if err != nil {
+		return "", "", err
+	}
+	return CustomRepository + ":" + tag, containerfile, nil
+}
+
+// CustomTag is the tag a customized image is cached under. It changes with the base image digest,
+// the generated Containerfile and the files in the build context, so any of them changing causes a rebuild.
+func CustomTag(baseDigest string, containerfile string, contextDir string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\n%v", baseDigest, containerfile)
+	if contextDir != "" {
+		if err := hashDir(h, contextDir); err != nil {
+			return "", fmt.Errorf("failed to read the %v context: %v", CustomizeKey, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// hashDir writes the path, mode and content of everything in dir to w
+func hashDir(w io.Writer, dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\x00%v\x00%v\x00", filepath.ToSlash(rel), info.Mode())
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(w, target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(w, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
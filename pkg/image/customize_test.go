@@ -0,0 +1,102 @@
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBuildContainerfile(t *testing.T) {
+	dir := t.TempDir()
+	fragment := filepath.Join(dir, "Containerfile.custom")
+	if err := os.WriteFile(fragment, []byte("COPY vimrc /root/.vimrc"), 0600); err != nil {
+		t.Fatalf("Failed to write fragment: %v", err)
+	}
+
+	c := Customization{Packages: []string{"neovim", "jq"}, Run: []string{"pip install yq"}, Containerfile: fragment}
+	containerfile, err := c.BuildContainerfile("localhost/ocm-container:latest", "sha256:base")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	expected := `FROM localhost/ocm-container:latest
+LABEL io.openshift.occ.customize.base="sha256:base"
+RUN dnf install -y neovim jq && dnf clean all
+RUN pip install yq
+COPY vimrc /root/.vimrc
+`
+	if containerfile != expected {
+		t.Fatalf("Expected\n%v\ngot\n%v", expected, containerfile)
+	}
+	c.Containerfile = filepath.Join(dir, "missing")
+	if _, err := c.BuildContainerfile("localhost/ocm-container:latest", "sha256:base"); err == nil {
+		t.Fatalf("Expected an error for a missing fragment")
+	}
+}
+
+func TestCustomTag(t *testing.T) {
+	tag, err := CustomTag("sha256:base", "FROM base\n", "")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	same, _ := CustomTag("sha256:base", "FROM base\n", "")
+	if len(tag) != 12 || tag != same {
+		t.Fatalf("Expected a stable 12 character tag, got %v", tag)
+	}
+	if newer, _ := CustomTag("sha256:newer", "FROM base\n", ""); tag == newer {
+		t.Fatalf("Expected a new base digest to change the tag")
+	}
+	if changed, _ := CustomTag("sha256:base", "FROM base\nRUN true\n", ""); tag == changed {
+		t.Fatalf("Expected a new Containerfile to change the tag")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "vimrc"), []byte("set number"), 0600); err != nil {
+		t.Fatalf("Failed to write context file: %v", err)
+	}
+	withContext, err := CustomTag("sha256:base", "FROM base\n", dir)
+	if err != nil || withContext == tag {
+		t.Fatalf("Expected the context to change the tag, got %v %v", withContext, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vimrc"), []byte("set nonumber"), 0600); err != nil {
+		t.Fatalf("Failed to write context file: %v", err)
+	}
+	if edited, _ := CustomTag("sha256:base", "FROM base\n", dir); edited == withContext {
+		t.Fatalf("Expected an edited context file to change the tag")
+	}
+	if _, err := CustomTag("sha256:base", "FROM base\n", filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("Expected an error for a missing context")
+	}
+}
+
+func TestCustomizationRef(t *testing.T) {
+	c := Customization{Packages: []string{"neovim"}}
+	ref, containerfile, err := c.Ref("localhost/ocm-container:latest", "sha256:base")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	tag, _ := CustomTag("sha256:base", containerfile, "")
+	if ref != CustomRepository+":"+tag || !strings.HasPrefix(containerfile, "FROM localhost/ocm-container:latest\n") {
+		t.Fatalf("Unexpected ref %v for Containerfile\n%v", ref, containerfile)
+	}
+}
+
+func TestCustomizationFromConfig(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	v := viper.New()
+	if c, err := CustomizationFromConfig(v); err != nil || !c.Empty() {
+		t.Fatalf("Expected no customization, got %+v %v", c, err)
+	}
+
+	v.Set(CustomizeKey, map[string]any{"packages": []string{"neovim"}, "containerfile": "~/.config/occ/Containerfile", "context": "~/.config/occ/custom"})
+	c, err := CustomizationFromConfig(v)
+	if err != nil || c.Empty() {
+		t.Fatalf("Expected a customization, got %+v %v", c, err)
+	}
+	if c.Containerfile != filepath.Join(home, ".config/occ/Containerfile") || c.Context != filepath.Join(home, ".config/occ/custom") || strings.Join(c.Packages, " ") != "neovim" {
+		t.Fatalf("Unexpected customization %+v", c)
+	}
+}
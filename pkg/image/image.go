@@ -0,0 +1,112 @@
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/openshift/occ/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Key is the config section describing the session image
+	Key = "image"
+
+	// PullKey is the config key of the pull policy, also set with --pull
+	PullKey = "pull"
+
+	// BuildContextKey is the directory, Containerfile or git URL occ image build and update build from
+	BuildContextKey = "image.build_context"
+
+	// ContainerfileKey is the Containerfile to build with, relative to the build context
+	ContainerfileKey = "image.containerfile"
+
+	DefaultRegistry   = "localhost"
+	DefaultRepository = "ocm-container"
+	DefaultTag        = "latest"
+)
+
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Ref is the image sessions run. When Digest is set, it's pulled and run by digest.
+type Ref struct {
+	Registry   string `mapstructure:"registry"`
+	Repository string `mapstructure:"repository"`
+	Tag        string `mapstructure:"tag"`
+	Digest     string `mapstructure:"digest"`
+	// AuthFile holds the registry credentials, defaults to the containers auth.json
+	AuthFile string `mapstructure:"auth_file"`
+}
+
+// Name is the registry and repository of the image, without a tag or digest
+func (r Ref) Name() string {
+	if r.Registry == "" {
+		return r.Repository
+	}
+	return r.Registry + "/" + r.Repository
+}
+
+func (r Ref) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Validate checks the ref is a valid image reference
+func (r Ref) Validate() error {
+	if r.Repository == "" {
+		return fmt.Errorf("image repository is required")
+	}
+	if r.Digest != "" && !digestPattern.MatchString(r.Digest) {
+		return fmt.Errorf("invalid image digest %q, expected sha256:<64 hex characters>", r.Digest)
+	}
+	if _, err := reference.ParseNormalizedNamed(r.String()); err != nil {
+		return fmt.Errorf("invalid image %v: %v", r, err)
+	}
+	return nil
+}
+
+// FromConfig reads the image from the config, filling in the defaults.
+// A non-empty tag, such as from --tag, overrides the configured tag and digest.
+func FromConfig(v *viper.Viper, tag string) (Ref, error) {
+	ref := Ref{Registry: DefaultRegistry, Repository: DefaultRepository, Tag: DefaultTag}
+	if err := v.UnmarshalKey(Key, &ref); err != nil {
+		return ref, fmt.Errorf("invalid %v in config: %v", Key, err)
+	}
+	ref.AuthFile = config.ExpandHome(ref.AuthFile)
+	if tag != "" {
+		ref.Tag, ref.Digest = tag, ""
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = DefaultTag
+	}
+	return ref, ref.Validate()
+}
+
+// PullPolicy decides when the image is pulled before a session starts
+type PullPolicy string
+
+const (
+	PullAlways  PullPolicy = "always"
+	PullMissing PullPolicy = "missing"
+	PullNever   PullPolicy = "never"
+)
+
+// ParsePullPolicy parses a pull policy, defaulting to PullMissing
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	switch p := PullPolicy(strings.ToLower(s)); p {
+	case "":
+		return PullMissing, nil
+	case PullAlways, PullMissing, PullNever:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown pull policy %q, expected %v, %v or %v", s, PullAlways, PullMissing, PullNever)
+	}
+}
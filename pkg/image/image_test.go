@@ -0,0 +1,93 @@
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestFromConfig(t *testing.T) {
+	type test struct {
+		name        string
+		config      map[string]any
+		tag         string
+		expected    string
+		expectedErr bool
+	}
+
+	tests := []test{
+		{name: "defaults", expected: "localhost/ocm-container:latest"},
+		{
+			name:     "registry image",
+			config:   map[string]any{"registry": "quay.io", "repository": "app-sre/ocm-container", "tag": "stable"},
+			expected: "quay.io/app-sre/ocm-container:stable",
+		},
+		{
+			name:     "pinned digest",
+			config:   map[string]any{"registry": "quay.io", "repository": "app-sre/ocm-container", "digest": testDigest},
+			expected: "quay.io/app-sre/ocm-container:latest@" + testDigest,
+		},
+		{
+			name:     "tag flag overrides the tag and digest",
+			config:   map[string]any{"tag": "stable", "digest": testDigest},
+			tag:      "candidate",
+			expected: "localhost/ocm-container:candidate",
+		},
+		{name: "invalid digest", config: map[string]any{"digest": "sha256:abc"}, expectedErr: true},
+		{name: "invalid repository", config: map[string]any{"repository": "Not Valid"}, expectedErr: true},
+		{name: "empty repository", config: map[string]any{"repository": ""}, expectedErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := viper.New()
+			if tc.config != nil {
+				v.Set(Key, tc.config)
+			}
+
+			ref, err := FromConfig(v, tc.tag)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v", ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if ref.String() != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, ref)
+			}
+		})
+	}
+}
+
+func TestParsePullPolicy(t *testing.T) {
+	tests := map[string]PullPolicy{"": PullMissing, "always": PullAlways, "Missing": PullMissing, "never": PullNever}
+	for s, expected := range tests {
+		if p, err := ParsePullPolicy(s); err != nil || p != expected {
+			t.Errorf("Expected %q to parse as %v, got %v %v", s, expected, p, err)
+		}
+	}
+	if _, err := ParsePullPolicy("sometimes"); err == nil {
+		t.Errorf("Expected an unknown policy to fail")
+	}
+}
+
+func TestFromConfigAuthFile(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	v := viper.New()
+	v.Set(Key, map[string]any{"auth_file": "~/.config/containers/auth.json"})
+
+	ref, err := FromConfig(v, "")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if expected := filepath.Join(home, ".config/containers/auth.json"); ref.AuthFile != expected {
+		t.Fatalf("Expected the auth file to be expanded to %v, got %v", expected, ref.AuthFile)
+	}
+}
@@ -0,0 +1,165 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	containersimage "github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/openshift/occ/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// VerifyKey is the config section describing how the session image's signature is verified
+	VerifyKey = "verify"
+
+	// KeyTypeSigstore is a sigstore public key, such as one created by cosign generate-key
+	KeyTypeSigstore = "sigstore"
+	// KeyTypeGPG is an armored or binary GPG public keyring
+	KeyTypeGPG = "gpg"
+)
+
+// sigstoreAttachments makes signatures be looked up as sigstore attachments in the registry, where cosign writes them
+const sigstoreAttachments = "default-docker:\n  use-sigstore-attachments: true\n"
+
+// Verification configures the signature check of the session image.
+// When it isn't empty, sessions only start once the image's signature is accepted.
+type Verification struct {
+	// Policy is a containers-policy.json file, used instead of the system wide one
+	Policy string `mapstructure:"policy"`
+	// Key is the public key images must be signed with when there's no Policy
+	Key string `mapstructure:"key"`
+	// KeyType is the kind of Key, defaults to KeyTypeSigstore
+	KeyType string `mapstructure:"key_type"`
+	// RegistriesDir configures where signatures are looked up, see containers-registries.d(5)
+	RegistriesDir string `mapstructure:"registries_d"`
+}
+
+// VerificationFromConfig reads the verify section of the config
+func VerificationFromConfig(v *viper.Viper) (Verification, error) {
+	var verify Verification
+	if err := v.UnmarshalKey(VerifyKey, &verify); err != nil {
+		return verify, fmt.Errorf("invalid %v in config: %v", VerifyKey, err)
+	}
+	for _, path := range []*string{&verify.Policy, &verify.Key, &verify.RegistriesDir} {
+		*path = config.ExpandHome(*path)
+	}
+
+	if verify.Policy != "" && verify.Key != "" {
+		return verify, fmt.Errorf("invalid %v in config: set either policy or key, not both", VerifyKey)
+	}
+	switch verify.KeyType {
+	case "":
+		verify.KeyType = KeyTypeSigstore
+	case KeyTypeSigstore, KeyTypeGPG:
+	default:
+		return verify, fmt.Errorf("invalid %v in config: unknown key_type %q, expected %v or %v", VerifyKey, verify.KeyType, KeyTypeSigstore, KeyTypeGPG)
+	}
+	return verify, nil
+}
+
+// Enabled reports whether signatures are verified
+func (v Verification) Enabled() bool {
+	return v.Policy != "" || v.Key != ""
+}
+
+// NewPolicy loads the policy file, or builds one which only accepts images of repository signed with the key
+func (v Verification) NewPolicy(repository string) (*signature.Policy, error) {
+	if v.Policy != "" {
+		policy, err := signature.NewPolicyFromFile(v.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the signature policy %v: %v", v.Policy, err)
+		}
+		return policy, nil
+	}
+	if v.Key == "" {
+		return nil, fmt.Errorf("no signature policy or key configured")
+	}
+	if _, err := os.Stat(v.Key); err != nil {
+		return nil, fmt.Errorf("failed to read the public key: %v", err)
+	}
+
+	var requirement signature.PolicyRequirement
+	var err error
+	if v.KeyType == KeyTypeGPG {
+		requirement, err = signature.NewPRSignedByKeyPath(signature.SBKeyTypeGPGKeys, v.Key, signature.NewPRMMatchRepoDigestOrExact())
+	} else {
+		requirement, err = signature.NewPRSigstoreSignedKeyPath(v.Key, signature.NewPRMMatchRepoDigestOrExact())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &signature.Policy{
+		Default: signature.PolicyRequirements{signature.NewPRReject()},
+		Transports: map[string]signature.PolicyTransportScopes{
+			docker.Transport.Name(): {repository: {requirement}},
+		},
+	}, nil
+}
+
+// Verify checks the manifest with the given digest, in the registry of ref, is signed according to the policy.
+// Images built locally aren't in a registry, so they can't be verified.
+func (v Verification) Verify(ctx context.Context, ref string, imageDigest string, authFile string) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("invalid image %v: %v", ref, err)
+	}
+	if reference.Domain(named) == DefaultRegistry {
+		return fmt.Errorf("%v is built locally, only images pulled from a registry can be verified", ref)
+	}
+	policy, err := v.NewPolicy(named.Name())
+	if err != nil {
+		return err
+	}
+
+	d, err := digest.Parse(imageDigest)
+	if err != nil {
+		return fmt.Errorf("%v has no registry digest, pull it again to verify it: %v", ref, err)
+	}
+	canonical, err := reference.WithDigest(reference.TrimNamed(named), d)
+	if err != nil {
+		return err
+	}
+	imageRef, err := docker.NewReference(canonical)
+	if err != nil {
+		return err
+	}
+	sys := &types.SystemContext{AuthFilePath: authFile, RegistriesDirPath: v.RegistriesDir}
+	if sys.RegistriesDirPath == "" && v.Policy == "" && v.KeyType != KeyTypeGPG {
+		dir, err := os.MkdirTemp("", "occ_registries.d")
+		if err != nil {
+			return fmt.Errorf("failed to create a tempdir for the registries.d config: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		if err := os.WriteFile(filepath.Join(dir, "occ.yaml"), []byte(sigstoreAttachments), 0600); err != nil {
+			return fmt.Errorf("failed to write the registries.d config: %v", err)
+		}
+		sys.RegistriesDirPath = dir
+	}
+	return verifyReference(ctx, policy, sys, imageRef)
+}
+
+// verifyReference evaluates the policy against the signatures of the image
+func verifyReference(ctx context.Context, policy *signature.Policy, sys *types.SystemContext, ref types.ImageReference) error {
+	policyContext, err := signature.NewPolicyContext(policy)
+	if err != nil {
+		return fmt.Errorf("invalid signature policy: %v", err)
+	}
+	defer policyContext.Destroy()
+
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = policyContext.IsRunningImageAllowed(ctx, containersimage.UnparsedInstance(src, nil))
+	return err
+}
@@ -0,0 +1,152 @@
+package image
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containers/image/v5/directory"
+	"github.com/containers/image/v5/signature"
+	"github.com/spf13/viper"
+)
+
+const testManifest = `{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", "size": 2},
+	"layers": []
+}`
+
+func TestVerificationFromConfig(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		name        string
+		verify      map[string]interface{}
+		expected    Verification
+		expectedErr string
+	}{
+		{
+			name:     "not configured",
+			expected: Verification{KeyType: KeyTypeSigstore},
+		},
+		{
+			name:     "sigstore key",
+			verify:   map[string]interface{}{"key": "~/.config/occ/cosign.pub"},
+			expected: Verification{Key: filepath.Join(home, ".config/occ/cosign.pub"), KeyType: KeyTypeSigstore},
+		},
+		{
+			name:     "policy",
+			verify:   map[string]interface{}{"policy": "/etc/occ/policy.json", "registries_d": "/etc/occ/registries.d"},
+			expected: Verification{Policy: "/etc/occ/policy.json", KeyType: KeyTypeSigstore, RegistriesDir: "/etc/occ/registries.d"},
+		},
+		{
+			name:        "policy and key",
+			verify:      map[string]interface{}{"policy": "/etc/occ/policy.json", "key": "/etc/occ/cosign.pub"},
+			expectedErr: "either policy or key",
+		},
+		{
+			name:        "unknown key type",
+			verify:      map[string]interface{}{"key": "/etc/occ/key.pem", "key_type": "x509"},
+			expectedErr: "unknown key_type",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := viper.New()
+			if test.verify != nil {
+				v.Set(VerifyKey, test.verify)
+			}
+			verify, err := VerificationFromConfig(v)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("Expected an error containing %q but got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if verify != test.expected {
+				t.Fatalf("Expected %+v but got %+v", test.expected, verify)
+			}
+			if verify.Enabled() != (test.verify != nil) {
+				t.Fatalf("Expected Enabled to be %v", test.verify != nil)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	policyFile := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(policyFile, []byte(`{"default": [{"type": "reject"}]}`), 0600); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	policy, err := Verification{Key: key, KeyType: KeyTypeGPG}.NewPolicy("quay.io/app-sre/ocm-container")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(policy.Transports["docker"]["quay.io/app-sre/ocm-container"]) != 1 {
+		t.Fatalf("Expected the repository to require the key, got %+v", policy.Transports)
+	}
+
+	if _, err := (Verification{Policy: policyFile}).NewPolicy("quay.io/app-sre/ocm-container"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if _, err := (Verification{Policy: filepath.Join(dir, "missing.json")}).NewPolicy("quay.io/app-sre/ocm-container"); err == nil {
+		t.Fatalf("Expected an error for a missing policy file")
+	}
+	if _, err := (Verification{Key: filepath.Join(dir, "missing.pub")}).NewPolicy("quay.io/app-sre/ocm-container"); err == nil {
+		t.Fatalf("Expected an error for a missing key")
+	}
+}
+
+func TestVerifyLocalImage(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	verify := Verification{Key: key, KeyType: KeyTypeSigstore}
+	err := verify.Verify(context.Background(), "localhost/ocm-container:latest", "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", "")
+	if err == nil || !strings.Contains(err.Error(), "built locally") {
+		t.Fatalf("Expected a locally built image to be refused, got %v", err)
+	}
+
+	err = verify.Verify(context.Background(), "quay.io/app-sre/ocm-container:latest", "44136fa355b3", "")
+	if err == nil || !strings.Contains(err.Error(), "no registry digest") {
+		t.Fatalf("Expected an image ID to be refused, got %v", err)
+	}
+}
+
+func TestVerifyReference(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(testManifest), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	ref, err := directory.NewReference(dir)
+	if err != nil {
+		t.Fatalf("Failed to create reference: %v", err)
+	}
+
+	accept := &signature.Policy{Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}}
+	if err := verifyReference(context.Background(), accept, nil, ref); err != nil {
+		t.Fatalf("Expected the image to be accepted but got %v", err)
+	}
+
+	requirement, err := signature.NewPRSignedByKeyPath(signature.SBKeyTypeGPGKeys, filepath.Join(dir, "key.gpg"), signature.NewPRMMatchRepoDigestOrExact())
+	if err != nil {
+		t.Fatalf("Failed to create requirement: %v", err)
+	}
+	signed := &signature.Policy{Default: signature.PolicyRequirements{requirement}}
+	if err := verifyReference(context.Background(), signed, nil, ref); err == nil {
+		t.Fatalf("Expected an unsigned image to be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/occ/pkg/audit"
+	"github.com/openshift/occ/pkg/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// auditStart records the session before it starts, so no session runs without a record.
+// It returns nil when there's no audit log to write to.
+func auditStart(ctx context.Context, eng engine.Engine, spec engine.Spec, containerID string, recordingPath string, opts Options) (*audit.Record, error) {
+	if opts.AuditFile == "" {
+		return nil, nil
+	}
+	digest, err := eng.ImageDigest(ctx, spec.Image)
+	if err != nil {
+		log.Debugf("Unable to resolve the digest of %v for the audit log: %v", spec.Image, err)
+	}
+
+	record := &audit.Record{
+		Event:       audit.EventStart,
+		SessionID:   containerID,
+		SessionName: spec.Name,
+		ClusterID:   opts.ClusterID,
+		OCMUser:     opts.OCMUser,
+		User:        audit.CurrentUser(),
+		Profile:     opts.Profile,
+		Image:       spec.Image,
+		ImageDigest: digest,
+		Exec:        opts.Exec,
+		Recording:   recordingPath,
+		Start:       time.Now().UTC(),
+	}
+	if err := audit.Append(opts.AuditFile, *record); err != nil {
+		return nil, fmt.Errorf("failed to record the session in the audit log: %v", err)
+	}
+	return record, nil
+}
+
+// auditEnd records how the session ended, either with result or with the error it failed with
+func auditEnd(record *audit.Record, opts Options, result *Result, launchErr error) {
+	if record == nil {
+		return
+	}
+	end := time.Now().UTC()
+	r := *record
+	r.Event, r.End = audit.EventEnd, &end
+	switch {
+	case launchErr != nil:
+		r.Error = launchErr.Error()
+	case result.Detached:
+		r.Detached = true
+	default:
+		exitCode := result.ExitCode
+		r.ExitCode = &exitCode
+	}
+	if err := audit.Append(opts.AuditFile, r); err != nil {
+		log.Warnf("Unable to record the end of session %v in the audit log: %v", r.SessionName, err)
+	}
+}
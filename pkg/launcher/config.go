@@ -0,0 +1,82 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openshift/occ/pkg/audit"
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/openshift/occ/pkg/recording"
+	"github.com/spf13/viper"
+)
+
+// OptionsFromConfig builds the options for a session on the cluster from the occ config alone
+func OptionsFromConfig(v *viper.Viper, clusterID string) (Options, error) {
+	engineName, conn := engine.ConnectionFromConfig(v)
+	opts := Options{
+		EngineName:        engineName,
+		EngineSocket:      conn.Socket,
+		EngineSSHIdentity: conn.SSHIdentity,
+		EngineSSHInsecure: conn.SSHInsecure,
+		ConfigPath:        v.ConfigFileUsed(),
+		ClusterID:         clusterID,
+		OCMUser:           v.GetString(config.OCMUserKey),
+		OCMUrl:            v.GetString(config.OCMUrlKey),
+		// The token may live in a secret store, so it's only resolved when the session is built
+		OfflineAccessTokenSource: config.OfflineAccessToken(v, config.Profile),
+		OpsUtilsDir:              v.GetString(config.OpsUtilsDirKey),
+		OpsUtilsDirRW:            v.GetBool(config.OpsUtilsDirRWKey),
+		SSHAuthSock:              os.Getenv("SSH_AUTH_SOCK"),
+		Streams:                  DefaultStreams(),
+		AuditFile:                audit.FileFromConfig(v, config.DefaultConfigFileLocation),
+		Profile:                  config.Profile,
+		Record:                   recording.FromConfig(v, config.DefaultDataLocation),
+	}
+	opts.HomeDir, _ = os.UserHomeDir()
+
+	ref, err := image.FromConfig(v, "")
+	if err != nil {
+		return opts, err
+	}
+	opts.Image, opts.AuthFile = ref.String(), ref.AuthFile
+	if opts.Pull, err = image.ParsePullPolicy(v.GetString(image.PullKey)); err != nil {
+		return opts, err
+	}
+	if opts.Verify, err = image.VerificationFromConfig(v); err != nil {
+		return opts, err
+	}
+	if opts.Customize, err = image.CustomizationFromConfig(v); err != nil {
+		return opts, err
+	}
+
+	if err := v.UnmarshalKey(config.MountsKey, &opts.Mounts); err != nil {
+		return opts, fmt.Errorf("invalid %v in config: %v", config.MountsKey, err)
+	}
+
+	if err := v.UnmarshalKey(config.ProvidersKey, &opts.Providers); err != nil {
+		return opts, fmt.Errorf("invalid %v in config: %v", config.ProvidersKey, err)
+	}
+	for i := range opts.Providers {
+		opts.Providers[i].Env = upperKeys(opts.Providers[i].Env)
+	}
+
+	opts.Env = upperKeys(v.GetStringMapString(config.EnvKey))
+	opts.EnvPassthrough = v.GetStringSlice(config.EnvPassthroughKey)
+	if len(opts.EnvPassthrough) > 0 {
+		opts.HostEnv = os.Environ()
+	}
+	return opts, nil
+}
+
+// upperKeys upper-cases env variable names read from the config. Config keys are
+// case insensitive, and env variables are conventionally upper case.
+func upperKeys(env map[string]string) map[string]string {
+	upper := make(map[string]string, len(env))
+	for k, val := range env {
+		upper[strings.ToUpper(k)] = val
+	}
+	return upper
+}
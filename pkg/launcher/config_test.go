@@ -0,0 +1,27 @@
+package launcher
+
+import (
+	"testing"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/spf13/viper"
+)
+
+func TestOptionsFromConfig(t *testing.T) {
+	v := viper.New()
+	v.Set(config.OCMUserKey, "testUser")
+	v.Set(config.EnvKey, map[string]any{"editor": "vim"})
+	v.Set(config.ProvidersKey, []map[string]any{{"name": "vault", "env": map[string]any{"vault_addr": "https://vault"}}})
+
+	opts, err := OptionsFromConfig(v, "1234")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if opts.ClusterID != "1234" || opts.OCMUser != "testUser" || opts.Image != image.DefaultRegistry+"/"+image.DefaultRepository+":"+image.DefaultTag {
+		t.Fatalf("Unexpected options %+v", opts)
+	}
+	if opts.Env["EDITOR"] != "vim" || opts.Providers[0].Env["VAULT_ADDR"] != "https://vault" {
+		t.Fatalf("Expected env variable names to be upper-cased, got %v and %v", opts.Env, opts.Providers[0].Env)
+	}
+}
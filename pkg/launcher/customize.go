@@ -0,0 +1,59 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/occ/pkg/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// customImage returns the customized image for base, building it unless one for the same base digest,
+// Containerfile and context is cached
+func customImage(ctx context.Context, eng engine.Engine, base string, opts Options) (string, error) {
+	baseDigest, err := eng.ImageDigest(ctx, base)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the digest of %v: %v", base, err)
+	}
+	ref, containerfile, err := opts.Customize.Ref(base, baseDigest)
+	if err != nil {
+		return "", err
+	}
+	exists, err := eng.ImageExists(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to look for image %v: %v", ref, err)
+	}
+	if exists {
+		log.Debugf("Using the cached customization of %v, %v", base, ref)
+		return ref, nil
+	}
+
+	dir, err := os.MkdirTemp("", "occ_customize")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a tempdir for the Containerfile: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	containerfilePath := filepath.Join(dir, "Containerfile")
+	if err := os.WriteFile(containerfilePath, []byte(containerfile), 0600); err != nil {
+		return "", fmt.Errorf("failed to write the Containerfile: %v", err)
+	}
+	// Without a configured context the build only sees the Containerfile
+	contextDir := opts.Customize.Context
+	if contextDir == "" {
+		contextDir = dir
+	}
+
+	log.Infof("Building your customization of %v as %v", base, ref)
+	_, err = eng.BuildImage(ctx, engine.BuildOptions{
+		ContextDir:    contextDir,
+		Containerfile: containerfilePath,
+		Tags:          []string{ref},
+		AuthFile:      opts.AuthFile,
+	}, opts.Streams.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to build the customization of %v: %v", base, err)
+	}
+	return ref, nil
+}
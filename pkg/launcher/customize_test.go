@@ -0,0 +1,80 @@
+package launcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/occ/pkg/image"
+)
+
+func TestCustomImage(t *testing.T) {
+	opts := Options{Customize: image.Customization{Packages: []string{"neovim"}}}
+
+	eng := &fakeEngine{missingImage: true}
+	ref, err := customImage(context.Background(), eng, DefaultImage, opts)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !strings.HasPrefix(ref, image.CustomRepository+":") {
+		t.Fatalf("Expected a customized image, got %v", ref)
+	}
+	if len(eng.built) != 1 || !strings.Contains(eng.built[0], "FROM "+DefaultImage) || !strings.Contains(eng.built[0], "neovim") {
+		t.Fatalf("Expected the customization to be built on the base image, got %v", eng.built)
+	}
+	if strings.Join(eng.contextFiles, ",") != "Containerfile" {
+		t.Fatalf("Expected an empty build context without a configured one, got %v", eng.contextFiles)
+	}
+
+	// The fake now has the image, so it's reused rather than rebuilt
+	cached, err := customImage(context.Background(), eng, DefaultImage, opts)
+	if err != nil || cached != ref || len(eng.built) != 1 {
+		t.Fatalf("Expected the cached image %v to be used, got %v %v after %v builds", ref, cached, err, len(eng.built))
+	}
+}
+
+func TestCustomImageContext(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "vimrc"), []byte("set number"), 0600); err != nil {
+		t.Fatalf("Failed to write context file: %v", err)
+	}
+	opts := Options{Customize: image.Customization{Run: []string{"true"}, Context: dir}}
+
+	eng := &fakeEngine{missingImage: true}
+	ref, err := customImage(context.Background(), eng, DefaultImage, opts)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if strings.Join(eng.contextFiles, ",") != "vimrc" {
+		t.Fatalf("Expected the configured build context, got %v", eng.contextFiles)
+	}
+
+	// Editing a file in the context makes a new image, which the fake doesn't have yet
+	eng.missingImage = true
+	if err := os.WriteFile(filepath.Join(dir, "vimrc"), []byte("set nonumber"), 0600); err != nil {
+		t.Fatalf("Failed to write context file: %v", err)
+	}
+	rebuilt, err := customImage(context.Background(), eng, DefaultImage, opts)
+	if err != nil || rebuilt == ref || len(eng.built) != 2 {
+		t.Fatalf("Expected the edited context to be rebuilt, got %v %v after %v builds", rebuilt, err, len(eng.built))
+	}
+}
+
+func TestLaunchCustomized(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	opts := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", GOOS: "linux", DisableConsolePort: true}
+	opts.Customize = image.Customization{Run: []string{"echo customized"}}
+	eng := &fakeEngine{}
+	opts.Engine = eng
+	if _, err := Launch(context.Background(), opts); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !strings.HasPrefix(eng.created.Image, image.CustomRepository+":") {
+		t.Fatalf("Expected the session to run the customized image, got %v", eng.created.Image)
+	}
+}
@@ -0,0 +1,75 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/openshift/occ/pkg/session"
+	log "github.com/sirupsen/logrus"
+)
+
+// DryRun is what Launch would create a session with
+type DryRun struct {
+	// Spec is the spec the container would be created with, with secrets redacted
+	Spec engine.Spec
+	// CustomImage is the customized image the session would run instead of Spec.Image, if any
+	CustomImage string
+	// Copies are the files copied into a remote session rather than bind mounted
+	Copies []specs.Mount
+}
+
+// NewDryRun makes the same decisions as Launch about secrets, the customized image and the files
+// copied into a remote session, without contacting the engine or creating anything
+func NewDryRun(opts Options) (DryRun, error) {
+	spec, err := NewSpec(opts)
+	if err != nil {
+		return DryRun{}, err
+	}
+
+	var d DryRun
+	if !opts.Customize.Empty() {
+		if d.CustomImage, err = dryRunCustomImage(spec.Image, opts); err != nil {
+			return DryRun{}, newError(PhaseCustomize, err)
+		}
+	}
+	if engine.IsRemote(dryRunHost(opts)) {
+		var warnings []string
+		d.Copies, warnings = remoteMounts(&spec)
+		for _, warning := range warnings {
+			log.Warn(warning)
+		}
+	}
+	// Docker Engine doesn't support secrets outside of swarm mode, so they stay plain env there
+	if opts.EngineName != engine.Docker {
+		for _, name := range secretEnvNames(spec) {
+			useSecret(&spec, name, session.SecretName(spec.Name, name))
+		}
+	}
+	d.Spec = Redact(spec)
+	return d, nil
+}
+
+// dryRunCustomImage returns the customized image of base. Its tag depends on the digest of base, which
+// is only known without asking the engine when the image is pinned by digest.
+func dryRunCustomImage(base string, opts Options) (string, error) {
+	if i := strings.LastIndex(base, "@"); i != -1 {
+		ref, _, err := opts.Customize.Ref(base, base[i+1:])
+		return ref, err
+	}
+	return fmt.Sprintf("%v:<tag from the digest of %v>", image.CustomRepository, base), nil
+}
+
+// dryRunHost is the engine URI Launch would connect to, as far as it's known without connecting
+func dryRunHost(opts Options) string {
+	if opts.EngineSocket != "" {
+		return opts.EngineSocket
+	}
+	if opts.EngineName == engine.Docker {
+		return os.Getenv("DOCKER_HOST")
+	}
+	return os.Getenv("CONTAINER_HOST")
+}
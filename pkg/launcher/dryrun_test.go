@@ -0,0 +1,87 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+)
+
+func TestNewDryRun(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	baseOptions := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", GOOS: "linux", OfflineAccessToken: "token"}
+
+	t.Run("the token is a secret with podman", func(t *testing.T) {
+		d, err := NewDryRun(baseOptions)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if _, ok := d.Spec.Env["OFFLINE_ACCESS_TOKEN"]; ok {
+			t.Fatalf("Expected the token not to be passed as env, got %v", d.Spec.Env)
+		}
+		if d.Spec.SecretEnv["OFFLINE_ACCESS_TOKEN"] != "occ-1234-offline-access-token" {
+			t.Fatalf("Expected the token to be passed as a secret, got %v", d.Spec.SecretEnv)
+		}
+	})
+
+	t.Run("the token is redacted env with docker", func(t *testing.T) {
+		opts := baseOptions
+		opts.EngineName = engine.Docker
+		d, err := NewDryRun(opts)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if d.Spec.Env["OFFLINE_ACCESS_TOKEN"] != Redacted || len(d.Spec.SecretEnv) != 0 {
+			t.Fatalf("Expected the token to be passed as redacted env, got %v and secrets %v", d.Spec.Env, d.Spec.SecretEnv)
+		}
+	})
+
+	t.Run("customized image", func(t *testing.T) {
+		opts := baseOptions
+		opts.Customize = image.Customization{Packages: []string{"htop"}}
+		d, err := NewDryRun(opts)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if !strings.HasPrefix(d.CustomImage, image.CustomRepository+":<") {
+			t.Fatalf("Expected the custom tag to depend on the image digest, got %v", d.CustomImage)
+		}
+
+		opts.Image = "quay.io/app-sre/ocm-container:latest@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		if d, err = NewDryRun(opts); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		expected, _, _ := opts.Customize.Ref(opts.Image, "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+		if d.CustomImage != expected {
+			t.Fatalf("Expected the custom image of a pinned image to be %v, got %v", expected, d.CustomImage)
+		}
+	})
+
+	t.Run("files are copied to a remote engine", func(t *testing.T) {
+		opts := baseOptions
+		opts.HomeDir = t.TempDir()
+		if err := os.MkdirAll(filepath.Join(opts.HomeDir, ".ssh"), 0700); err != nil {
+			t.Fatalf("Failed to create test ssh dir: %v", err)
+		}
+		opts.EngineSocket = "ssh://core@build-host/run/podman/podman.sock"
+		d, err := NewDryRun(opts)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		for _, m := range d.Spec.Mounts {
+			if m.Type == define.TypeBind {
+				t.Fatalf("Expected no bind mounts on a remote engine, got %+v", m)
+			}
+		}
+		if len(d.Copies) == 0 {
+			t.Fatalf("Expected files to be copied to the remote engine")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package launcher
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reservedEnv are the variables occ sets itself, which the configured env can't override
+var reservedEnv = map[string]bool{
+	"USER":                  true,
+	"OFFLINE_ACCESS_TOKEN":  true,
+	"OCM_URL":               true,
+	"INITIAL_CLUSTER_LOGIN": true,
+	"SSH_AUTH_SOCK":         true,
+}
+
+func makeEnvMap(opts Options) (map[string]string, error) {
+	envMap := map[string]string{}
+
+	if opts.OCMUser != "" {
+		envMap["USER"] = opts.OCMUser
+	}
+
+	offlineAccessToken := opts.OfflineAccessToken
+	if offlineAccessToken == "" && opts.OfflineAccessTokenSource != nil {
+		token, err := opts.OfflineAccessTokenSource()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the offline access token: %v", err)
+		}
+		offlineAccessToken = token
+	}
+	if offlineAccessToken != "" {
+		envMap["OFFLINE_ACCESS_TOKEN"] = offlineAccessToken
+	}
+
+	if opts.OCMUrl != "" {
+		envMap["OCM_URL"] = opts.OCMUrl
+	}
+
+	if opts.ClusterID != "" {
+		envMap["INITIAL_CLUSTER_LOGIN"] = opts.ClusterID
+	}
+
+	var sshAuthSock string
+	if opts.GOOS == "darwin" {
+		sshAuthSock = "/tmp/ssh/Listeners"
+	} else {
+		sshAuthSock = "/tmp/ssh.sock"
+	}
+	envMap["SSH_AUTH_SOCK"] = sshAuthSock
+	return envMap, nil
+}
+
+// userEnv returns the host variables matching EnvPassthrough, overlaid with the static Env.
+// Setting a reserved variable in Env is an error, passthrough patterns just skip them.
+func userEnv(opts Options) (map[string]string, error) {
+	for _, pattern := range opts.EnvPassthrough {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid env_passthrough pattern %q: %v", pattern, err)
+		}
+	}
+
+	envMap := map[string]string{}
+	for _, kv := range opts.HostEnv {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" || !matchesAny(opts.EnvPassthrough, name) {
+			continue
+		}
+		if reservedEnv[name] {
+			log.Debugf("Not passing through %v, it's set by occ", name)
+			continue
+		}
+		envMap[name] = value
+	}
+
+	for name, value := range opts.Env {
+		if name == "" || strings.Contains(name, "=") {
+			return nil, fmt.Errorf("invalid env variable name %q", name)
+		}
+		if reservedEnv[name] {
+			return nil, fmt.Errorf("env variable %v is set by occ and can't be overridden", name)
+		}
+		envMap[name] = value
+	}
+	return envMap, nil
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testOptions returns the options the run tests have always used, for the given OS
+func testOptions(goos string) Options {
+	return Options{
+		ClusterID:          "1234",
+		OCMUser:            "testUser",
+		OfflineAccessToken: "testToken",
+		OCMUrl:             "testOcmUrl",
+		OpsUtilsDir:        "testOpsUtilsDir",
+		OpsUtilsDirRW:      true,
+		GOOS:               goos,
+	}
+}
+
+func TestMakeEnvMap(t *testing.T) {
+	type test struct {
+		name             string
+		goos             string
+		expectedAuthSock string
+	}
+
+	tests := []test{
+		{name: "darwin os", goos: "darwin", expectedAuthSock: "/tmp/ssh/Listeners"},
+		{name: "non-darwin os", goos: "not darwin", expectedAuthSock: "/tmp/ssh.sock"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			envMap, err := makeEnvMap(testOptions(tc.goos))
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			var failures []string
+
+			if val := envMap["USER"]; val != "testUser" {
+				failures = append(failures, fmt.Sprintf("USER was %v, expected %v", val, "testUser"))
+			}
+
+			if val := envMap["OFFLINE_ACCESS_TOKEN"]; val != "testToken" {
+				failures = append(failures, fmt.Sprintf("OFFLINE_ACCESS_TOKEN was %v, expected %v", val, "testToken"))
+			}
+
+			if val := envMap["OCM_URL"]; val != "testOcmUrl" {
+				failures = append(failures, fmt.Sprintf("OCM_URL was %v, expected %v", val, "testOcmUrl"))
+			}
+
+			if val := envMap["INITIAL_CLUSTER_LOGIN"]; val != "1234" {
+				failures = append(failures, fmt.Sprintf("INITIAL_CLUSTER_LOGIN was %v, expected %v", val, "1234"))
+			}
+
+			if val := envMap["SSH_AUTH_SOCK"]; val != tc.expectedAuthSock {
+				failures = append(failures, fmt.Sprintf("INITIAL_CLUSTER_LOGIN was %v, expected %v", val, tc.expectedAuthSock))
+			}
+
+			if len(failures) > 0 {
+				t.Fatalf(strings.Join(failures, "\n"))
+			}
+		})
+	}
+}
+
+func TestUserEnv(t *testing.T) {
+	hostEnv := []string{
+		"AWS_PROFILE=dev",
+		"AWS_REGION=us-east-1",
+		"TZ=Europe/Dublin",
+		"HTTPS_PROXY=http://proxy:3128",
+		"SSH_AUTH_SOCK=/run/user/1000/ssh.sock",
+		"HOME=/home/test",
+	}
+
+	type test struct {
+		name        string
+		env         map[string]string
+		passthrough []string
+		expected    map[string]string
+		expectedErr string
+	}
+
+	tests := []test{
+		{
+			name:     "Nothing configured",
+			expected: map[string]string{},
+		},
+		{
+			name:        "Passthrough with globs",
+			passthrough: []string{"AWS_*", "TZ", "*_PROXY"},
+			expected: map[string]string{
+				"AWS_PROFILE": "dev",
+				"AWS_REGION":  "us-east-1",
+				"TZ":          "Europe/Dublin",
+				"HTTPS_PROXY": "http://proxy:3128",
+			},
+		},
+		{
+			name:        "Passthrough skips reserved variables",
+			passthrough: []string{"SSH_*", "TZ"},
+			expected:    map[string]string{"TZ": "Europe/Dublin"},
+		},
+		{
+			name:        "Static env overrides passthrough",
+			env:         map[string]string{"EDITOR": "vim", "TZ": "UTC"},
+			passthrough: []string{"TZ"},
+			expected:    map[string]string{"EDITOR": "vim", "TZ": "UTC"},
+		},
+		{
+			name:        "Static env can't override reserved variables",
+			env:         map[string]string{"OFFLINE_ACCESS_TOKEN": "other"},
+			expectedErr: "can't be overridden",
+		},
+		{
+			name:        "Invalid variable name",
+			env:         map[string]string{"A=B": "C"},
+			expectedErr: "invalid env variable name",
+		},
+		{
+			name:        "Invalid pattern",
+			passthrough: []string{"AWS_["},
+			expectedErr: "invalid env_passthrough pattern",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{Env: tc.env, EnvPassthrough: tc.passthrough, HostEnv: hostEnv}
+			envMap, err := userEnv(opts)
+
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if !reflect.DeepEqual(envMap, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, envMap)
+			}
+		})
+	}
+}
+
+func TestMakeEnvMapTokenSource(t *testing.T) {
+	opts := testOptions("linux")
+	opts.OfflineAccessToken = ""
+	opts.OfflineAccessTokenSource = func() (string, error) { return "storedToken", nil }
+
+	envMap, err := makeEnvMap(opts)
+	if err != nil || envMap["OFFLINE_ACCESS_TOKEN"] != "storedToken" {
+		t.Fatalf("Expected the token from the source, got %v (%v)", envMap["OFFLINE_ACCESS_TOKEN"], err)
+	}
+
+	opts.OfflineAccessTokenSource = func() (string, error) { return "", errors.New("locked") }
+	if _, err := makeEnvMap(opts); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("Expected the source error, got %v", err)
+	}
+
+	opts.OfflineAccessToken = "testToken"
+	if envMap, err := makeEnvMap(opts); err != nil || envMap["OFFLINE_ACCESS_TOKEN"] != "testToken" {
+		t.Fatalf("Expected the source to be skipped when a token is given, got %v (%v)", envMap["OFFLINE_ACCESS_TOKEN"], err)
+	}
+}
@@ -0,0 +1,425 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/audit"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	"github.com/openshift/occ/pkg/recording"
+	"github.com/openshift/occ/pkg/session"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ImageRepository is where the session image is built to
+	ImageRepository = image.DefaultRegistry + "/" + image.DefaultRepository
+
+	// DefaultImage is the image sessions run when Options.Image is empty
+	DefaultImage = ImageRepository + ":" + image.DefaultTag
+
+	// DefaultMacPrivateTempDir is where launchd creates the ssh agent socket on macOS
+	DefaultMacPrivateTempDir = "/private/tmp"
+)
+
+var (
+	// ErrConfigNotFound is returned when Options.ConfigPath doesn't exist
+	ErrConfigNotFound = errors.New("config file not found")
+
+	// ErrSessionExists is returned when a session with the same name is already running
+	ErrSessionExists = errors.New("session already exists")
+
+	// ErrInterrupted is returned when occ is interrupted or terminated before the session started
+	ErrInterrupted = errors.New("interrupted")
+
+	// ErrImageNotFound is returned when the image isn't available locally and the pull policy is never
+	ErrImageNotFound = errors.New("image not found")
+
+	// ErrSignature is returned when the image's signature isn't accepted by Options.Verify
+	ErrSignature = errors.New("image signature verification failed")
+)
+
+// Options configures a session launch. Everything occ reads from its config file and
+// flags ends up here, so the launcher can be embedded without the occ config.
+type Options struct {
+	// Engine is the container engine to launch with.
+	// If nil, Launch connects to EngineName at EngineSocket instead.
+	Engine       engine.Engine
+	EngineName   string
+	EngineSocket string
+	// EngineSSHIdentity and EngineSSHInsecure configure ssh:// podman sockets
+	EngineSSHIdentity string
+	EngineSSHInsecure bool
+
+	// ConfigPath is the occ config file, which is mounted read-only into the session
+	ConfigPath string
+	// HomeDir is the host directory credentials are mounted from
+	HomeDir string
+
+	// ClusterID is the cluster to log into, and names the session. It may be empty.
+	ClusterID string
+	// Image is the container image to run, defaults to DefaultImage
+	Image string
+	// Pull decides when Image is pulled before the session starts, defaults to image.PullMissing
+	Pull image.PullPolicy
+	// AuthFile holds the registry credentials, defaults to the containers auth.json
+	AuthFile string
+	// Verify checks the signature of Image before the session is created, unless SkipVerify is set
+	Verify     image.Verification
+	SkipVerify bool
+	// Customize is a personal layer built on top of Image, which sessions run instead when it isn't empty
+	Customize image.Customization
+	// Exec is an in-container script to run non-interactively instead of a shell
+	Exec               string
+	DisableConsolePort bool
+	// TTY and Interactive override whether the session gets a TTY and stdin.
+	// By default it gets both, unless Exec is set.
+	TTY         *bool
+	Interactive *bool
+
+	// AuditFile is where the session's start and end are recorded, nothing is recorded when it's empty
+	AuditFile string
+	// Profile is the config profile the session was started with, for the audit log
+	Profile string
+	// Record saves the session's terminal to an asciicast file, when Record.Dir is set
+	Record recording.Options
+
+	OCMUser            string
+	OCMUrl             string
+	OfflineAccessToken string
+	// OfflineAccessTokenSource resolves the token when OfflineAccessToken is empty.
+	// It's only called once the session env is built, so secret stores aren't unlocked needlessly.
+	OfflineAccessTokenSource func() (string, error)
+	OpsUtilsDir              string
+	OpsUtilsDirRW            bool
+	// Mounts are extra host paths to mount, on top of the ones occ always makes
+	Mounts []Mount
+
+	// Providers are added to, or override, the built-in credential integrations
+	Providers []Provider
+
+	// Env are extra variables to set in the session
+	Env map[string]string
+	// EnvPassthrough are glob patterns of HostEnv variables to copy into the session
+	EnvPassthrough []string
+	// HostEnv is the environment EnvPassthrough is matched against, usually os.Environ()
+	HostEnv []string
+
+	// SSHAuthSock is the host ssh agent socket, used on every OS but macOS
+	SSHAuthSock string
+	// GOOS is the host operating system, defaults to runtime.GOOS
+	GOOS string
+	// MacPrivateTempDir defaults to DefaultMacPrivateTempDir
+	MacPrivateTempDir string
+
+	// Streams are attached to the session. In exec mode Stdin is ignored.
+	Streams engine.Streams
+}
+
+// Result describes how a session ended
+type Result struct {
+	SessionName string
+	ContainerID string
+	// ExitCode of the session's main process, only set when the session wasn't detached
+	ExitCode int
+	// Detached is true when the user detached and the session is still running
+	Detached bool
+}
+
+// Phase is the step of a launch an error happened in
+type Phase string
+
+const (
+	PhaseConfig    Phase = "config"
+	PhaseConnect   Phase = "connect"
+	PhasePull      Phase = "pull"
+	PhaseVerify    Phase = "verify"
+	PhaseCustomize Phase = "customize"
+	PhaseCreate    Phase = "create"
+	PhaseAttach    Phase = "attach"
+	PhaseStart     Phase = "start"
+	PhaseCopy      Phase = "copy"
+	PhaseWait      Phase = "wait"
+)
+
+// Error is returned by Launch for any failure, so callers can tell which phase failed
+type Error struct {
+	Phase Phase
+	Err   error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func newError(phase Phase, err error) error {
+	return &Error{Phase: phase, Err: err}
+}
+
+// setDefaults fills in the options that have a sensible default
+func (opts *Options) setDefaults() {
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	if opts.GOOS == "" {
+		opts.GOOS = runtime.GOOS
+	}
+	if opts.MacPrivateTempDir == "" {
+		opts.MacPrivateTempDir = DefaultMacPrivateTempDir
+	}
+}
+
+// Connection is how Launch reaches the engine when Options.Engine is nil
+func (opts Options) Connection() engine.Connection {
+	return engine.Connection{Socket: opts.EngineSocket, SSHIdentity: opts.EngineSSHIdentity, SSHInsecure: opts.EngineSSHInsecure}
+}
+
+// NewSpec computes the container spec for a session without contacting the engine
+func NewSpec(opts Options) (engine.Spec, error) {
+	return newSpec(osFileSystemRead{}, opts)
+}
+
+func newSpec(fs fileSystemRead, opts Options) (engine.Spec, error) {
+	opts.setDefaults()
+
+	if _, err := fs.Stat(opts.ConfigPath); err != nil {
+		return engine.Spec{}, newError(PhaseConfig, fmt.Errorf("%w at %v. Run occ init to create one", ErrConfigNotFound, opts.ConfigPath))
+	}
+
+	mounts, err := makeMounts(fs, opts)
+	if err != nil {
+		return engine.Spec{}, newError(PhaseConfig, err)
+	}
+
+	// Provider env is overridden by the user's env, and occ's own env always wins
+	env, err := providerEnv(fs, opts)
+	if err != nil {
+		return engine.Spec{}, newError(PhaseConfig, err)
+	}
+	extraEnv, err := userEnv(opts)
+	if err != nil {
+		return engine.Spec{}, newError(PhaseConfig, err)
+	}
+	occEnv, err := makeEnvMap(opts)
+	if err != nil {
+		return engine.Spec{}, newError(PhaseConfig, err)
+	}
+	for _, envMap := range []map[string]string{extraEnv, occEnv} {
+		for k, v := range envMap {
+			env[k] = v
+		}
+	}
+
+	spec := engine.Spec{
+		Name:                session.Name(opts.ClusterID),
+		Image:               opts.Image,
+		Labels:              session.Labels(opts.ClusterID),
+		Stdin:               true,
+		Terminal:            true,
+		Remove:              true,
+		Privileged:          true,
+		Env:                 env,
+		Mounts:              mounts,
+		PublishExposedPorts: !opts.DisableConsolePort,
+	}
+	setExecMode(&spec, opts.Exec)
+	if opts.TTY != nil {
+		spec.Terminal = *opts.TTY
+	}
+	if opts.Interactive != nil {
+		spec.Stdin = *opts.Interactive
+	}
+	return spec, nil
+}
+
+// setExecMode configures the spec to run the given in-container script non-interactively.
+// The container exits once the script finishes, so no TTY or stdin is allocated.
+func setExecMode(s *engine.Spec, script string) {
+	if script == "" {
+		return
+	}
+	s.Command = []string{script}
+	s.Stdin = false
+	s.Terminal = false
+}
+
+// Launch creates, starts and attaches to a session, returning once it ends or the user detaches.
+// Interrupting or terminating occ stops the session, while a hang up detaches from it.
+// Any failure is returned as an *Error.
+func Launch(ctx context.Context, opts Options) (*Result, error) {
+	spec, err := NewSpec(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	eng := opts.Engine
+	if eng == nil {
+		eng, err = engine.New(ctx, opts.EngineName, opts.Connection())
+		if err != nil {
+			return nil, newError(PhaseConnect, fmt.Errorf("error building connection to the container engine: %v", err))
+		}
+	}
+
+	result := &Result{SessionName: spec.Name}
+	exists, err := eng.Exists(ctx, spec.Name)
+	if err != nil {
+		return nil, newError(PhaseConnect, fmt.Errorf("failed to check for an existing session: %v", err))
+	}
+	if exists {
+		return nil, newError(PhaseCreate, fmt.Errorf("%w: %v is already running, use occ attach %v to reattach to it or occ stop %v to end it", ErrSessionExists, spec.Name, spec.Name, spec.Name))
+	}
+
+	if err := pullImage(ctx, eng, spec.Image, opts); err != nil {
+		return nil, newError(PhasePull, err)
+	}
+	if err := verifyImage(ctx, eng, spec.Image, opts); err != nil {
+		return nil, newError(PhaseVerify, err)
+	}
+	if !opts.Customize.Empty() {
+		if spec.Image, err = customImage(ctx, eng, spec.Image, opts); err != nil {
+			return nil, newError(PhaseCustomize, err)
+		}
+	}
+
+	if removed, err := session.PruneSecrets(ctx, eng); err != nil {
+		log.Debugf("Unable to prune secrets of old sessions: %v", err)
+	} else if len(removed) > 0 {
+		log.Debugf("Removed secrets of old sessions: %v", removed)
+	}
+
+	// A remote engine can't see this host's files, so they're copied in once the container exists
+	var copies []specs.Mount
+	if engine.IsRemote(eng.Host()) {
+		var warnings []string
+		copies, warnings = remoteMounts(&spec)
+		for _, warning := range warnings {
+			log.Warn(warning)
+		}
+	}
+
+	// Signals are handled before anything is left behind for the session, so an interrupt cleans it up.
+	// The container is stopped by name, as an interrupt may come before it's created.
+	signals, stopSignals := NotifySignals(ctx, eng, spec.Name)
+	defer stopSignals()
+
+	if err := moveSecrets(ctx, eng, &spec); err != nil {
+		removeSecrets(ctx, eng, spec.Name)
+		return nil, newError(PhaseCreate, err)
+	}
+	// The secrets outlive the launch only when the user detaches, occ stop or attach clean them up then
+	defer func() {
+		if !result.Detached {
+			removeSecrets(ctx, eng, spec.Name)
+		}
+	}()
+	if err := interrupted(signals); err != nil {
+		return nil, newError(PhaseCreate, err)
+	}
+
+	result.ContainerID, err = eng.Create(ctx, spec)
+	if err != nil {
+		return nil, newError(PhaseCreate, fmt.Errorf("failed to create container: %v", err))
+	}
+
+	// From here on the container is privileged with credentials mounted, so any failure removes it
+	// rather than leave it behind
+	var record *audit.Record
+	stopRecording := func() {}
+	defer func() { stopRecording() }()
+	rollback := func(err error) error {
+		auditEnd(record, opts, result, err)
+		if rmErr := eng.Remove(ctx, result.ContainerID, true); rmErr != nil {
+			log.Warnf("Unable to remove container %v, run occ prune to remove it: %v", result.ContainerID, rmErr)
+		}
+		return err
+	}
+	if err := interrupted(signals); err != nil {
+		return nil, rollback(newError(PhaseCreate, err))
+	}
+
+	if err := copyMounts(ctx, eng, result.ContainerID, copies); err != nil {
+		return nil, rollback(newError(PhaseCopy, fmt.Errorf("there was an error copying files to the remote container: %v", err)))
+	}
+
+	// Without stdin, such as in exec mode, we just stream the session's output
+	streams := opts.Streams
+	if !spec.Stdin {
+		streams.Stdin = nil
+	}
+	// Sessions are recorded from the host side of the streams, so it works the same with or without a TTY
+	recordingPath, stop, err := startRecording(spec, result.ContainerID, opts, &streams)
+	if err != nil {
+		return nil, rollback(newError(PhaseAttach, err))
+	}
+	stopRecording = stop
+
+	// Attach before starting the container so no output from a short-lived script is lost
+	attachErr := make(chan error, 1)
+	attachReady := make(chan bool)
+	go func() {
+		attachErr <- eng.Attach(ctx, result.ContainerID, streams, attachReady)
+	}()
+
+	select {
+	case <-attachReady:
+	case err := <-attachErr:
+		return nil, rollback(newError(PhaseAttach, fmt.Errorf("there was an error attaching to the container: %v", err)))
+	}
+
+	if err := interrupted(signals); err != nil {
+		return nil, rollback(newError(PhaseStart, err))
+	}
+	if record, err = auditStart(ctx, eng, spec, result.ContainerID, recordingPath, opts); err != nil {
+		return nil, rollback(newError(PhaseStart, err))
+	}
+	if err := eng.Start(ctx, result.ContainerID); err != nil {
+		return nil, rollback(newError(PhaseStart, fmt.Errorf("failed to start container: %v", err)))
+	}
+	// A signal received while starting may have stopped the container before it was running
+	if err := interrupted(signals); err != nil {
+		return nil, rollback(newError(PhaseStart, err))
+	}
+
+	if !opts.DisableConsolePort {
+		err := copyPortmap(osFileSystemWrite{}, eng, builderCopier{}, ctx, result.ContainerID)
+		if err != nil {
+			return nil, rollback(newError(PhaseCopy, fmt.Errorf("there was an error copying portmap file to the container: %v", err)))
+		}
+	}
+
+	select {
+	case err = <-attachErr:
+	case <-signals.HangUp:
+		// There's no terminal left to restore or attach to, so the session is left to be reattached
+		log.Debugf("Hung up, leaving session %v running", result.SessionName)
+		result.Detached = true
+		auditEnd(record, opts, result, nil)
+		return result, nil
+	}
+	if err != nil {
+		if errors.Is(err, engine.ErrDetached) {
+			result.Detached = true
+			auditEnd(record, opts, result, nil)
+			return result, nil
+		}
+		return nil, rollback(newError(PhaseAttach, fmt.Errorf("there was an error attaching to the container: %v", err)))
+	}
+
+	result.ExitCode, err = eng.Wait(ctx, result.ContainerID)
+	if err != nil {
+		return nil, rollback(newError(PhaseWait, fmt.Errorf("failed to retrieve the container exit code: %v", err)))
+	}
+	log.Debugf("Session %v exited with code %v", result.SessionName, result.ExitCode)
+	auditEnd(record, opts, result, nil)
+	return result, nil
+}
+
+// DefaultStreams attaches a session to the standard streams of the occ process
+func DefaultStreams() engine.Streams {
+	return engine.Streams{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
@@ -0,0 +1,515 @@
+package launcher
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/openshift/occ/pkg/audit"
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/recording"
+	"github.com/openshift/occ/pkg/session"
+)
+
+func TestSetExecMode(t *testing.T) {
+	type test struct {
+		name             string
+		script           string
+		expectedCommand  []string
+		expectedStdin    bool
+		expectedTerminal bool
+	}
+
+	tests := []test{
+		{name: "no script keeps interactive shell", script: "", expectedCommand: nil, expectedStdin: true, expectedTerminal: true},
+		{name: "script runs non-interactively", script: "/root/sop-utils/check.sh", expectedCommand: []string{"/root/sop-utils/check.sh"}, expectedStdin: false, expectedTerminal: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &engine.Spec{Image: "test-image", Stdin: true, Terminal: true}
+
+			setExecMode(s, tc.script)
+
+			if strings.Join(s.Command, " ") != strings.Join(tc.expectedCommand, " ") {
+				t.Fatalf("Expected command %v, got %v", tc.expectedCommand, s.Command)
+			}
+			if s.Stdin != tc.expectedStdin {
+				t.Fatalf("Expected stdin to be %v, got %v", tc.expectedStdin, s.Stdin)
+			}
+			if s.Terminal != tc.expectedTerminal {
+				t.Fatalf("Expected terminal to be %v, got %v", tc.expectedTerminal, s.Terminal)
+			}
+		})
+	}
+}
+
+func TestNewSpec(t *testing.T) {
+	testfs := fstest.MapFS{"config_path": {Data: []byte{}}}
+
+	t.Run("missing config file", func(t *testing.T) {
+		_, err := newSpec(fstest.MapFS{}, Options{ConfigPath: "config_path", GOOS: "linux"})
+		var launchErr *Error
+		if !errors.As(err, &launchErr) || launchErr.Phase != PhaseConfig {
+			t.Fatalf("Expected a config phase error, got %v", err)
+		}
+		if !errors.Is(err, ErrConfigNotFound) {
+			t.Fatalf("Expected ErrConfigNotFound, got %v", err)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		spec, err := newSpec(testfs, Options{ConfigPath: "config_path", HomeDir: "home_dir", ClusterID: "1234", GOOS: "linux"})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if spec.Image != DefaultImage {
+			t.Fatalf("Expected image %v, got %v", DefaultImage, spec.Image)
+		}
+		if spec.Name != "occ-1234" || spec.Labels[session.ClusterLabel] != "1234" {
+			t.Fatalf("Expected the session to be named and labelled for cluster 1234, got %v %v", spec.Name, spec.Labels)
+		}
+		if !spec.Stdin || !spec.Terminal || !spec.Remove || !spec.Privileged || !spec.PublishExposedPorts {
+			t.Fatalf("Expected an interactive, ephemeral, privileged session with the console port, got %+v", spec)
+		}
+	})
+
+	t.Run("terminal overrides", func(t *testing.T) {
+		tty, interactive := false, true
+		spec, err := newSpec(testfs, Options{ConfigPath: "config_path", HomeDir: "home_dir", Exec: "/root/check.sh", TTY: &tty, Interactive: &interactive, GOOS: "linux"})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if !spec.Stdin || spec.Terminal {
+			t.Fatalf("Expected stdin without a TTY, got %+v", spec)
+		}
+	})
+
+	t.Run("exec mode without console port", func(t *testing.T) {
+		spec, err := newSpec(testfs, Options{ConfigPath: "config_path", HomeDir: "home_dir", Exec: "/root/check.sh", DisableConsolePort: true, GOOS: "linux"})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if spec.Stdin || spec.Terminal || spec.PublishExposedPorts {
+			t.Fatalf("Expected a non-interactive session without the console port, got %+v", spec)
+		}
+	})
+}
+
+func TestLaunch(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	baseOptions := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", GOOS: "linux", DisableConsolePort: true}
+
+	type test struct {
+		name             string
+		engine           *fakeEngine
+		expectedPhase    Phase
+		expectedExitCode int
+		expectedDetached bool
+		expectedRemoved  bool
+	}
+
+	tests := []test{
+		{name: "session exits cleanly", engine: &fakeEngine{}},
+		{name: "session exit code is returned", engine: &fakeEngine{exitCode: 3}, expectedExitCode: 3},
+		{name: "user detaches", engine: &fakeEngine{attachErr: engine.ErrDetached}, expectedDetached: true},
+		{name: "session already exists", engine: &fakeEngine{exists: true}, expectedPhase: PhaseCreate},
+		{name: "engine unreachable", engine: &fakeEngine{existsErr: errors.New("fail")}, expectedPhase: PhaseConnect},
+		{name: "pull fails", engine: &fakeEngine{missingImage: true, pullErr: errors.New("fail")}, expectedPhase: PhasePull},
+		{name: "create fails", engine: &fakeEngine{createErr: errors.New("fail")}, expectedPhase: PhaseCreate},
+		{name: "start fails", engine: &fakeEngine{startErr: errors.New("fail")}, expectedPhase: PhaseStart, expectedRemoved: true},
+		{name: "attach fails", engine: &fakeEngine{attachErr: errors.New("fail")}, expectedPhase: PhaseAttach, expectedRemoved: true},
+		{name: "wait fails", engine: &fakeEngine{waitErr: errors.New("fail")}, expectedPhase: PhaseWait, expectedRemoved: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := baseOptions
+			opts.Engine = tc.engine
+			result, err := Launch(context.Background(), opts)
+			if removed := len(tc.engine.removed) > 0; removed != tc.expectedRemoved {
+				t.Fatalf("Expected the container to be removed to be %v, got %v", tc.expectedRemoved, tc.engine.removed)
+			}
+
+			if tc.expectedPhase != "" {
+				var launchErr *Error
+				if !errors.As(err, &launchErr) || launchErr.Phase != tc.expectedPhase {
+					t.Fatalf("Expected a %v phase error, got %v", tc.expectedPhase, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if result.SessionName != "occ-1234" || result.ContainerID != "test-id" {
+				t.Fatalf("Unexpected session %v with container %v", result.SessionName, result.ContainerID)
+			}
+			if result.ExitCode != tc.expectedExitCode || result.Detached != tc.expectedDetached {
+				t.Fatalf("Expected exit code %v and detached %v, got %v and %v", tc.expectedExitCode, tc.expectedDetached, result.ExitCode, result.Detached)
+			}
+		})
+	}
+}
+
+// interruptEngine terminates occ while the session is created or started
+type interruptEngine struct {
+	*fakeEngine
+	t       *testing.T
+	during  string
+	stopped chan string
+}
+
+func (i *interruptEngine) Create(ctx context.Context, spec engine.Spec) (string, error) {
+	id, err := i.fakeEngine.Create(ctx, spec)
+	i.interrupt("create")
+	return id, err
+}
+
+func (i *interruptEngine) Start(ctx context.Context, id string) error {
+	err := i.fakeEngine.Start(ctx, id)
+	i.interrupt("start")
+	return err
+}
+
+func (i *interruptEngine) Stop(_ context.Context, nameOrID string, _ uint) error {
+	i.stopped <- nameOrID
+	return nil
+}
+
+func (i *interruptEngine) interrupt(step string) {
+	if i.during != step {
+		return
+	}
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		i.t.Fatalf("Failed to find the test process: %v", err)
+	}
+	if err := self.Signal(syscall.SIGTERM); err != nil {
+		i.t.Fatalf("Failed to terminate: %v", err)
+	}
+	// Wait for the signal to be handled, as it would be while a real engine is busy
+	<-i.stopped
+}
+
+func TestLaunchInterrupted(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	opts := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", GOOS: "linux", DisableConsolePort: true}
+
+	for _, tc := range []struct {
+		during        string
+		expectedPhase Phase
+	}{
+		{during: "create", expectedPhase: PhaseCreate},
+		{during: "start", expectedPhase: PhaseStart},
+	} {
+		t.Run("interrupted during "+tc.during, func(t *testing.T) {
+			eng := &interruptEngine{fakeEngine: &fakeEngine{}, t: t, during: tc.during, stopped: make(chan string)}
+			opts.Engine = eng
+			_, err := Launch(context.Background(), opts)
+
+			var launchErr *Error
+			if !errors.Is(err, ErrInterrupted) || !errors.As(err, &launchErr) || launchErr.Phase != tc.expectedPhase {
+				t.Fatalf("Expected an interrupted %v phase error, got %v", tc.expectedPhase, err)
+			}
+			if len(eng.removed) != 1 || eng.removed[0] != "test-id" {
+				t.Fatalf("Expected the interrupted session to be removed, got %v", eng.removed)
+			}
+		})
+	}
+}
+
+func TestLaunchAudit(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	opts := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", OCMUser: "alice", Profile: "prod", GOOS: "linux", DisableConsolePort: true, AuditFile: auditFile}
+
+	opts.Engine = &fakeEngine{exitCode: 3}
+	if _, err := Launch(context.Background(), opts); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	opts.Engine = &fakeEngine{startErr: errors.New("fail")}
+	if _, err := Launch(context.Background(), opts); err == nil {
+		t.Fatalf("Expected the start to fail")
+	}
+
+	f, err := os.Open(auditFile)
+	if err != nil {
+		t.Fatalf("Expected an audit log: %v", err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	var records []audit.Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r audit.Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Invalid audit record %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected a start and end record per launch, got %+v", records)
+	}
+	first := records[1]
+	if first.Event != audit.EventEnd || first.ExitCode == nil || *first.ExitCode != 3 || first.ClusterID != "1234" || first.OCMUser != "alice" || first.Profile != "prod" || first.ImageDigest != "sha256:test" || first.End == nil {
+		t.Fatalf("Expected the end of the first session with its exit code, got %+v", first)
+	}
+	if failed := records[3]; failed.Event != audit.EventEnd || failed.Error == "" || failed.ExitCode != nil {
+		t.Fatalf("Expected the failed start to be recorded, got %+v", failed)
+	}
+}
+
+func TestLaunchRecord(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	dir := t.TempDir()
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	opts := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", Exec: "/root/check.sh", GOOS: "linux", DisableConsolePort: true, AuditFile: auditFile, Record: recording.Options{Dir: dir}}
+	opts.Engine = &fakeEngine{output: "all good\n"}
+	if _, err := Launch(context.Background(), opts); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	path, err := recording.Find(dir, "1234")
+	if err != nil {
+		t.Fatalf("Expected the session to be recorded: %v", err)
+	}
+	var out strings.Builder
+	if err := recording.Play(context.Background(), mustOpen(t, path), &out, 1000); err != nil || out.String() != "all good\n" {
+		t.Fatalf("Expected the recording to play the session output, got %q %v", out.String(), err)
+	}
+
+	data, _ := io.ReadAll(mustOpen(t, auditFile))
+	if !strings.Contains(string(data), `"recording":"`+path+`"`) {
+		t.Fatalf("Expected the audit log to point to the recording, got %v", string(data))
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %v: %v", path, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestLaunchSecrets(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	opts := Options{ConfigPath: configFile.Name(), HomeDir: t.TempDir(), ClusterID: "1234", GOOS: "linux", DisableConsolePort: true, OfflineAccessToken: "testToken"}
+
+	t.Run("secrets are passed through the engine and removed afterwards", func(t *testing.T) {
+		eng := &fakeEngine{secrets: map[string]string{}}
+		opts.Engine = eng
+		if _, err := Launch(context.Background(), opts); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if _, ok := eng.created.Env["OFFLINE_ACCESS_TOKEN"]; ok {
+			t.Fatalf("Expected the token to be left out of the env")
+		}
+		if name := eng.created.SecretEnv["OFFLINE_ACCESS_TOKEN"]; name != "occ-1234-offline-access-token" {
+			t.Fatalf("Expected the token to come from a secret, got %v", eng.created.SecretEnv)
+		}
+		if eng.createdSecrets["occ-1234-offline-access-token"] != "testToken" {
+			t.Fatalf("Expected the secret to hold the token, got %v", eng.createdSecrets)
+		}
+		if len(eng.secrets) != 0 {
+			t.Fatalf("Expected the secrets to be removed once the session ended, got %v", eng.secrets)
+		}
+	})
+
+	t.Run("secrets are kept for detached sessions", func(t *testing.T) {
+		eng := &fakeEngine{secrets: map[string]string{}, attachErr: engine.ErrDetached}
+		opts.Engine = eng
+		if _, err := Launch(context.Background(), opts); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if _, ok := eng.secrets["occ-1234-offline-access-token"]; !ok {
+			t.Fatalf("Expected the secret to be kept, got %v", eng.secrets)
+		}
+	})
+
+	t.Run("engines without secrets use env", func(t *testing.T) {
+		eng := &fakeEngine{}
+		opts.Engine = eng
+		if _, err := Launch(context.Background(), opts); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if eng.created.Env["OFFLINE_ACCESS_TOKEN"] != "testToken" || len(eng.created.SecretEnv) != 0 {
+			t.Fatalf("Expected the token in the env, got %v %v", eng.created.Env, eng.created.SecretEnv)
+		}
+	})
+}
+
+func TestLaunchRemote(t *testing.T) {
+	configFile, err := os.CreateTemp(t.TempDir(), "config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	homeDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(homeDir, ".ssh"), 0700); err != nil {
+		t.Fatalf("Failed to create test ssh dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(homeDir, ".ssh", "id_ed25519"), []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write test key: %v", err)
+	}
+	opts := Options{ConfigPath: configFile.Name(), HomeDir: homeDir, ClusterID: "1234", GOOS: "linux", DisableConsolePort: true}
+
+	eng := &fakeEngine{host: "ssh://core@build-host/run/podman/podman.sock"}
+	opts.Engine = eng
+	if _, err := Launch(context.Background(), opts); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	for _, m := range eng.created.Mounts {
+		if m.Type == define.TypeBind {
+			t.Fatalf("Expected no bind mounts on a remote engine, got %+v", m)
+		}
+	}
+	copied := strings.Join(eng.copied, " ")
+	if !strings.Contains(copied, "root/.ssh/id_ed25519") || !strings.Contains(copied, "root/.config/occ") {
+		t.Fatalf("Expected the ssh key and config to be copied, got %v", eng.copied)
+	}
+}
+
+// fakeEngine fails wherever it's told to. It only supports secrets when secrets isn't nil.
+type fakeEngine struct {
+	engine.Engine
+	exists    bool
+	existsErr error
+	createErr error
+	startErr  error
+	attachErr error
+	waitErr   error
+	exitCode  int
+
+	created        engine.Spec
+	secrets        map[string]string
+	createdSecrets map[string]string
+
+	host string
+	// missingImage makes the image unavailable until it's pulled
+	missingImage bool
+	pullErr      error
+	pulled       []string
+	// built are the Containerfiles of the images built
+	built []string
+	// contextFiles are the files in the build contexts of the images built
+	contextFiles []string
+	// copied are the names in the archives copied into the container
+	copied []string
+	// removed are the containers removed
+	removed []string
+	// output is what the session prints once attached
+	output string
+}
+
+func (f *fakeEngine) Exists(context.Context, string) (bool, error) { return f.exists, f.existsErr }
+func (f *fakeEngine) Create(_ context.Context, spec engine.Spec) (string, error) {
+	f.created = spec
+	return "test-id", f.createErr
+}
+func (f *fakeEngine) List(context.Context, map[string]string) ([]engine.Container, error) {
+	return nil, nil
+}
+func (f *fakeEngine) CreateSecret(_ context.Context, name string, data []byte, _ map[string]string) error {
+	if f.secrets == nil {
+		return engine.ErrSecretsUnsupported
+	}
+	if f.createdSecrets == nil {
+		f.createdSecrets = map[string]string{}
+	}
+	f.secrets[name] = string(data)
+	f.createdSecrets[name] = string(data)
+	return nil
+}
+func (f *fakeEngine) ListSecrets(context.Context, map[string]string) ([]engine.Secret, error) {
+	var list []engine.Secret
+	for name := range f.secrets {
+		list = append(list, engine.Secret{Name: name, Labels: map[string]string{session.SessionLabel: "occ-1234"}})
+	}
+	return list, nil
+}
+func (f *fakeEngine) RemoveSecret(_ context.Context, name string) error {
+	delete(f.secrets, name)
+	return nil
+}
+func (f *fakeEngine) Host() string                                      { return f.host }
+func (f *fakeEngine) ImageExists(context.Context, string) (bool, error) { return !f.missingImage, nil }
+func (f *fakeEngine) PullImage(_ context.Context, ref string, _ string, _ io.Writer) error {
+	if f.pullErr != nil {
+		return f.pullErr
+	}
+	f.pulled = append(f.pulled, ref)
+	f.missingImage = false
+	return nil
+}
+func (f *fakeEngine) ImageDigest(context.Context, string) (string, error) { return "sha256:test", nil }
+func (f *fakeEngine) BuildImage(_ context.Context, options engine.BuildOptions, _ io.Writer) (string, error) {
+	containerfile, err := os.ReadFile(options.Containerfile)
+	if err != nil {
+		return "", err
+	}
+	f.built = append(f.built, string(containerfile))
+	entries, err := os.ReadDir(options.ContextDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		f.contextFiles = append(f.contextFiles, entry.Name())
+	}
+	f.missingImage = false
+	return "built-id", nil
+}
+func (f *fakeEngine) CopyToContainer(_ context.Context, _ string, _ string, reader io.Reader) error {
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.copied = append(f.copied, hdr.Name)
+	}
+}
+func (f *fakeEngine) Start(context.Context, string) error { return f.startErr }
+func (f *fakeEngine) Attach(_ context.Context, _ string, streams engine.Streams, ready chan bool) error {
+	if f.attachErr != nil && !errors.Is(f.attachErr, engine.ErrDetached) {
+		return f.attachErr
+	}
+	ready <- true
+	// Engines copy what the session prints to Tee
+	if f.output != "" && streams.Tee != nil {
+		_, _ = io.WriteString(streams.Tee, f.output)
+	}
+	return f.attachErr
+}
+func (f *fakeEngine) Wait(context.Context, string) (int, error) { return f.exitCode, f.waitErr }
+func (f *fakeEngine) Remove(_ context.Context, nameOrID string, _ bool) error {
+	f.removed = append(f.removed, nameOrID)
+	return nil
+}
@@ -0,0 +1,113 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+type fileSystemRead interface {
+	ReadDir(name string) ([]os.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+}
+
+type osFileSystemRead struct{}
+
+func (osFileSystemRead) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+func (osFileSystemRead) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+
+func makeMounts(fs fileSystemRead, opts Options) ([]specs.Mount, error) {
+	homeDir := opts.HomeDir
+	mountSlice := []specs.Mount{
+		{
+			Destination: "/root/.ssh/sockets",
+			Type:        define.TypeTmpfs,
+		},
+		{
+			Source:      opts.ConfigPath,
+			Destination: "/root/.config/occ",
+			Options:     []string{"ro"},
+			Type:        define.TypeBind,
+		},
+		{
+			Source:      homeDir + "/.ssh",
+			Destination: "/root/.ssh",
+			Options:     []string{"ro"},
+			Type:        define.TypeBind,
+		},
+	}
+
+	var sshAgentMount specs.Mount
+	if opts.GOOS == "darwin" {
+		agentLocation, err := macAgentLocation(fs, opts.MacPrivateTempDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve agent location: %v", err)
+		}
+		sshAgentMount = specs.Mount{
+			Source:      opts.MacPrivateTempDir + "/" + agentLocation,
+			Destination: "/tmp/ssh",
+			Options:     []string{"ro"},
+			Type:        define.TypeBind,
+		}
+	} else {
+		sshAgentMount = specs.Mount{
+			Source:      opts.SSHAuthSock,
+			Destination: "/tmp/ssh.sock",
+			Options:     []string{"ro"},
+			Type:        define.TypeBind,
+		}
+	}
+	mountSlice = append(mountSlice, sshAgentMount)
+
+	if opts.OpsUtilsDir != "" {
+		opsUtilsDirMount := specs.Mount{
+			Source:      opts.OpsUtilsDir,
+			Destination: "/root/sop-utils",
+			Type:        define.TypeBind,
+		}
+		if opts.OpsUtilsDirRW {
+			opsUtilsDirMount.Options = []string{"rw"}
+		} else {
+			opsUtilsDirMount.Options = []string{"ro"}
+		}
+
+		mountSlice = append(mountSlice, opsUtilsDirMount)
+	}
+
+	destinations := mountDestinations(mountSlice)
+	integrationMounts, err := providerMounts(fs, opts, destinations)
+	if err != nil {
+		return nil, err
+	}
+	mountSlice = append(mountSlice, integrationMounts...)
+
+	extraMounts, err := userMounts(fs, opts, destinations)
+	if err != nil {
+		return nil, err
+	}
+	return append(mountSlice, extraMounts...), nil
+}
+
+func macAgentLocation(fs fileSystemRead, privateTempDir string) (string, error) {
+	dirs, err := fs.ReadDir(privateTempDir)
+	if err != nil {
+		return "", err
+	}
+
+	if len(dirs) < 1 {
+		return "", errors.New(fmt.Sprintf("no dirs found at %v", privateTempDir))
+	}
+
+	for _, dir := range dirs {
+		if dirName := dir.Name(); strings.Contains(dirName, "com.apple.launchd") {
+			return dirName, nil
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("no dir found at %v containing com.apple.launchd", privateTempDir))
+}
@@ -0,0 +1,297 @@
+package launcher
+
+import (
+	"fmt"
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMakeMounts(t *testing.T) {
+	type test struct {
+		name                 string
+		testfs               fstest.MapFS
+		expectedMounts       int
+		expectGCPMount       bool
+		expectAWSMount       bool
+		expectOpsUtilsDir    bool
+		expectOpsUtilsDirRw  bool
+		expectPagerDutyToken bool
+		goos                 string
+	}
+
+	tests := []test{
+		{
+			name: "All mounts",
+			testfs: fstest.MapFS{
+				"home_dir/.config/gcloud":                    {Mode: fs.ModeDir},
+				"home_dir/.aws":                              {Mode: fs.ModeDir},
+				"home_dir/.config/pagerduty-cli/config.json": {Data: []byte{}},
+				"private/tmp/com.apple.launchd.test":         {Mode: fs.ModeDir},
+			},
+			expectedMounts:       12,
+			expectGCPMount:       true,
+			expectAWSMount:       true,
+			expectOpsUtilsDir:    true,
+			expectOpsUtilsDirRw:  true,
+			expectPagerDutyToken: true,
+			goos:                 "darwin",
+		},
+		{
+			name: "No GCP mounts",
+			testfs: fstest.MapFS{
+				"home_dir/.aws": {Mode: fs.ModeDir},
+				"home_dir/.config/pagerduty-cli/config.json": {Data: []byte{}},
+				"private/tmp/com.apple.launchd.test":         {Mode: fs.ModeDir},
+			},
+			expectedMounts:       8,
+			expectGCPMount:       false,
+			expectAWSMount:       true,
+			expectOpsUtilsDir:    true,
+			expectOpsUtilsDirRw:  true,
+			expectPagerDutyToken: true,
+			goos:                 "darwin",
+		},
+		{
+			name: "No AWS mounts",
+			testfs: fstest.MapFS{
+				"home_dir/.config/gcloud":                    {Mode: fs.ModeDir},
+				"home_dir/.config/pagerduty-cli/config.json": {Data: []byte{}},
+				"private/tmp/com.apple.launchd.test":         {Mode: fs.ModeDir},
+			},
+			expectedMounts:       10,
+			expectGCPMount:       true,
+			expectAWSMount:       false,
+			expectOpsUtilsDir:    true,
+			expectOpsUtilsDirRw:  true,
+			expectPagerDutyToken: true,
+			goos:                 "darwin",
+		},
+		{
+			name: "No Ops Utils mounts",
+			testfs: fstest.MapFS{
+				"home_dir/.config/gcloud":                    {Mode: fs.ModeDir},
+				"home_dir/.aws":                              {Mode: fs.ModeDir},
+				"home_dir/.config/pagerduty-cli/config.json": {Data: []byte{}},
+				"private/tmp/com.apple.launchd.test":         {Mode: fs.ModeDir},
+			},
+			expectedMounts:       11,
+			expectGCPMount:       true,
+			expectAWSMount:       true,
+			expectOpsUtilsDir:    false,
+			expectOpsUtilsDirRw:  true,
+			expectPagerDutyToken: true,
+			goos:                 "darwin",
+		},
+		{
+			name: "All mounts Ops Utils read only",
+			testfs: fstest.MapFS{
+				"home_dir/.config/gcloud":                    {Mode: fs.ModeDir},
+				"home_dir/.aws":                              {Mode: fs.ModeDir},
+				"home_dir/.config/pagerduty-cli/config.json": {Data: []byte{}},
+				"private/tmp/com.apple.launchd.test":         {Mode: fs.ModeDir},
+			},
+			expectedMounts:       12,
+			expectGCPMount:       true,
+			expectAWSMount:       true,
+			expectOpsUtilsDir:    true,
+			expectOpsUtilsDirRw:  false,
+			expectPagerDutyToken: true,
+			goos:                 "darwin",
+		},
+		{
+			name: "No PagerDuty mount",
+			testfs: fstest.MapFS{
+				"home_dir/.config/gcloud":            {Mode: fs.ModeDir},
+				"home_dir/.aws":                      {Mode: fs.ModeDir},
+				"private/tmp/com.apple.launchd.test": {Mode: fs.ModeDir},
+			},
+			expectedMounts:       11,
+			expectGCPMount:       true,
+			expectAWSMount:       true,
+			expectOpsUtilsDir:    true,
+			expectOpsUtilsDirRw:  true,
+			expectPagerDutyToken: false,
+			goos:                 "darwin",
+		},
+		{
+			name: "All mounts non-darwin",
+			testfs: fstest.MapFS{
+				"home_dir/.config/gcloud":                    {Mode: fs.ModeDir},
+				"home_dir/.aws":                              {Mode: fs.ModeDir},
+				"home_dir/.config/pagerduty-cli/config.json": {Data: []byte{}},
+				"private/tmp/com.apple.launchd.test":         {Mode: fs.ModeDir},
+			},
+			expectedMounts:       12,
+			expectGCPMount:       true,
+			expectAWSMount:       true,
+			expectOpsUtilsDir:    true,
+			expectOpsUtilsDirRw:  true,
+			expectPagerDutyToken: true,
+			goos:                 "not-darwin",
+		},
+	}
+
+	configPath := "config_path"
+	homeDir := "home_dir"
+	macPrivateTempDir := "private/tmp"
+	sshAuthSock := "ssh_auth_sock"
+
+	for _, tc := range tests {
+		opts := Options{
+			ConfigPath:        configPath,
+			HomeDir:           homeDir,
+			MacPrivateTempDir: macPrivateTempDir,
+			SSHAuthSock:       sshAuthSock,
+			GOOS:              tc.goos,
+			OpsUtilsDirRW:     tc.expectOpsUtilsDirRw,
+		}
+		if tc.expectOpsUtilsDir {
+			opts.OpsUtilsDir = "testOpsUtilsDir"
+		}
+
+		t.Run(tc.name, func(t *testing.T) {
+			mounts, err := makeMounts(tc.testfs, opts)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if mountCount := len(mounts); mountCount != tc.expectedMounts {
+				t.Fatalf("Unexpected number of mounts. Expected %v but got %v", tc.expectedMounts, mountCount)
+			}
+
+			mountMap := map[string]specs.Mount{}
+			for _, mount := range mounts {
+				mountMap[mount.Destination] = mount
+			}
+
+			var failures []string
+
+			failures = append(failures, checkMount(mountMap, "", "/root/.ssh/sockets", []string{}, define.TypeTmpfs)...)
+			failures = append(failures, checkMount(mountMap, configPath, "/root/.config/occ", []string{"ro"}, define.TypeBind)...)
+			failures = append(failures, checkMount(mountMap, homeDir+"/.ssh", "/root/.ssh", []string{"ro"}, define.TypeBind)...)
+
+			if tc.goos == "darwin" {
+				failures = append(failures, checkMount(mountMap, macPrivateTempDir+"/com.apple.launchd.test", "/tmp/ssh", []string{"ro"}, define.TypeBind)...)
+			} else {
+				failures = append(failures, checkMount(mountMap, sshAuthSock, "/tmp/ssh.sock", []string{"ro"}, define.TypeBind)...)
+			}
+
+			if tc.expectGCPMount {
+				failures = append(failures, checkMount(mountMap, homeDir+"/.config/gcloud/active_config", "/root/.config/gcloud/active_config_readonly", []string{"ro"}, define.TypeBind)...)
+				failures = append(failures, checkMount(mountMap, homeDir+"/.config/gcloud/configurations/config_default", "/root/.config/gcloud/configurations/config_default_readonly", []string{"ro"}, define.TypeBind)...)
+				failures = append(failures, checkMount(mountMap, homeDir+"/.config/gcloud/credentials.db", "/root/.config/gcloud/credentials_readonly.db", []string{"ro"}, define.TypeBind)...)
+				failures = append(failures, checkMount(mountMap, homeDir+"/.config/gcloud/access_tokens.db", "/root/.config/gcloud/access_tokens_readonly.db", []string{"ro"}, define.TypeBind)...)
+			}
+
+			if tc.expectAWSMount {
+				failures = append(failures, checkMount(mountMap, homeDir+"/.aws/credentials", "/root/.aws/credentials", []string{"ro"}, define.TypeBind)...)
+				failures = append(failures, checkMount(mountMap, homeDir+"/.aws/config", "/root/.aws/config", []string{"ro"}, define.TypeBind)...)
+			}
+
+			if tc.expectOpsUtilsDir {
+				var opsUtilRwOptions []string
+				if tc.expectOpsUtilsDirRw {
+					opsUtilRwOptions = append(opsUtilRwOptions, "rw")
+				} else {
+					opsUtilRwOptions = append(opsUtilRwOptions, "ro")
+				}
+				failures = append(failures, checkMount(mountMap, opts.OpsUtilsDir, "/root/sop-utils", opsUtilRwOptions, define.TypeBind)...)
+			}
+
+			if tc.expectPagerDutyToken {
+				failures = append(failures, checkMount(mountMap, homeDir+"/.config/pagerduty-cli/config.json", "/root/.config/pagerduty-cli/config.json", []string{"ro"}, define.TypeBind)...)
+			}
+
+			if len(failures) > 0 {
+				t.Fatalf(strings.Join(failures, "\n"))
+			}
+		})
+	}
+}
+
+func TestMakeMountsMissingAgent(t *testing.T) {
+	opts := Options{HomeDir: "home_dir", MacPrivateTempDir: "private/tmp", GOOS: "darwin"}
+	_, err := makeMounts(fstest.MapFS{"private/tmp/com.foo": {Mode: fs.ModeDir}}, opts)
+	expected := "failed to retrieve agent location: no dir found at private/tmp containing com.apple.launchd"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected %v, got %v", expected, err)
+	}
+}
+
+func checkMount(mountMap map[string]specs.Mount, source string, destination string, options []string, mountType string) []string {
+	var failures []string
+	if mount, ok := mountMap[destination]; ok {
+		if mount.Source != source {
+			failures = append(failures, fmt.Sprintf("For mount with destination %v, expected source to be %v but was %v", mount.Destination, "home_dir/.ssh", mount.Source))
+		}
+		if mount.Type != mountType {
+			failures = append(failures, fmt.Sprintf("For mount with destination %v, expected type to be %v but was %v", mount.Destination, mountType, mount.Type))
+		}
+		for i := 0; i < len(options); i++ {
+			if mount.Options[i] != options[i] {
+				failures = append(failures, fmt.Sprintf("For mount with destination %v, expected options to contain %v", mount.Destination, strings.Join(options, ",")))
+				break
+			}
+		}
+	} else {
+		failures = append(failures, fmt.Sprintf("Expected mount to exist with destination %v but none was found", destination))
+	}
+	return failures
+}
+
+func TestMacAgentLocation(t *testing.T) {
+	type test struct {
+		name           string
+		testfs         fstest.MapFS
+		privateTempDir string
+		expectedResult string
+		expectedError  string
+	}
+
+	tests := []test{
+		{
+			name:           "Successfully finds agent location",
+			testfs:         fstest.MapFS{"private/tmp/com.apple.launchd.test": {Mode: fs.ModeDir}},
+			privateTempDir: "private/tmp",
+			expectedResult: "com.apple.launchd.test",
+			expectedError:  "",
+		},
+		{
+			name:           "Fails to read private temp dir",
+			testfs:         fstest.MapFS{},
+			privateTempDir: "foo",
+			expectedResult: "",
+			expectedError:  "open foo: file does not exist",
+		},
+		{
+			name:           "No dirs in private temp dir",
+			testfs:         fstest.MapFS{"private/tmp": {Mode: fs.ModeDir}},
+			privateTempDir: "private/tmp",
+			expectedResult: "",
+			expectedError:  "no dirs found at private/tmp",
+		},
+		{
+			name:           "No dirs containing agent",
+			testfs:         fstest.MapFS{"private/tmp/com.foo": {Mode: fs.ModeDir}},
+			privateTempDir: "private/tmp",
+			expectedResult: "",
+			expectedError:  "no dir found at private/tmp containing com.apple.launchd",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := macAgentLocation(tc.testfs, tc.privateTempDir)
+			if tc.expectedError != "" && err.Error() != tc.expectedError {
+				t.Fatalf("Did not receive the expected error.\nExpected: %v\nActual: %v", tc.expectedError, err.Error())
+			}
+			if result != tc.expectedResult {
+				t.Fatalf("Expected %v, but got %v", tc.expectedResult, result)
+			}
+		})
+	}
+}
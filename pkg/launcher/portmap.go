@@ -0,0 +1,118 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	buildahCopiah "github.com/containers/buildah/copier"
+	"github.com/containers/podman/v4/pkg/errorhandling"
+	"github.com/openshift/occ/pkg/engine"
+)
+
+const (
+	// ConsolePort is the container port the cluster console listens on
+	ConsolePort = "9999/tcp"
+	// PortmapDir is where the host ports of the console are written to, in a portmap file, once the session starts
+	PortmapDir = "/tmp"
+)
+
+type fileSystemWrite interface {
+	MkdirTemp(string, string) (string, error)
+	RemoveAll(string) error
+	Create(string) (*os.File, error)
+	Fprintln(w io.Writer, a ...any) (n int, err error)
+}
+
+type osFileSystemWrite struct{}
+
+func (osFileSystemWrite) MkdirTemp(dir string, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern)
+}
+func (osFileSystemWrite) RemoveAll(path string) error          { return os.RemoveAll(path) }
+func (osFileSystemWrite) Create(name string) (*os.File, error) { return os.Create(name) }
+func (osFileSystemWrite) Fprintln(w io.Writer, a ...any) (n int, err error) {
+	return fmt.Fprintln(w, a...)
+}
+
+// container is the part of engine.Engine needed to copy files into a running container
+type container interface {
+	Inspect(ctx context.Context, nameOrID string) (*engine.Container, error)
+	CopyToContainer(ctx context.Context, nameOrID string, path string, reader io.Reader) error
+}
+
+type copier interface {
+	Get(root string, directory string, options buildahCopiah.GetOptions, globs []string, bulkWriter io.Writer) error
+}
+
+type builderCopier struct{}
+
+func (builderCopier) Get(root string, directory string, options buildahCopiah.GetOptions, globs []string, bulkWriter io.Writer) error {
+	return buildahCopiah.Get(root, directory, options, globs, bulkWriter)
+}
+
+func copyPortmap(fs fileSystemWrite, container container, copier copier, ctx context.Context, containerId string) error {
+
+	tmpdir, err := fs.MkdirTemp("", "occ_portmaps")
+	if err != nil {
+		return fmt.Errorf("failed to create a tempdir for portmap: %v", err)
+	}
+	defer fs.RemoveAll(tmpdir)
+
+	data, err := container.Inspect(ctx, containerId)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %v", err)
+	}
+
+	hostPorts := data.Ports[ConsolePort]
+
+	portmapFile, err := fs.Create(tmpdir + "/portmap")
+	if err != nil {
+		return fmt.Errorf("failed to create portmap file: %v", err)
+	}
+
+	for _, port := range hostPorts {
+		_, err := fs.Fprintln(portmapFile, port)
+		if err != nil {
+			return fmt.Errorf("failed to write host port to portmap file: %v", err)
+		}
+	}
+
+	reader, writer := io.Pipe()
+	hostCopy := func() error {
+		defer writer.Close()
+		getOptions := buildahCopiah.GetOptions{
+			KeepDirectoryNames: true,
+		}
+		if err := copier.Get("/", "", getOptions, []string{portmapFile.Name()}, writer); err != nil {
+			return fmt.Errorf("error copying portmap file from host: %v", err)
+		}
+		return nil
+	}
+
+	containerCopy := func() error {
+		defer reader.Close()
+		if err := container.CopyToContainer(ctx, containerId, PortmapDir, reader); err != nil {
+			return fmt.Errorf("error copying portmap file to container: %v", err)
+		}
+		return nil
+	}
+
+	if err := doCopy(hostCopy, containerCopy); err != nil {
+		return fmt.Errorf("error copying portmap file from host to container: %v", err)
+	}
+	return nil
+}
+
+// Copied from https://github.com/containers/podman/blob/main/cmd/podman/containers/cp.go#L113
+func doCopy(hostCopyFunc func() error, containerCopyFunc func() error) error {
+	errChan := make(chan error)
+	go func() {
+		errChan <- hostCopyFunc()
+	}()
+	var copyErrors []error
+	copyErrors = append(copyErrors, containerCopyFunc())
+	copyErrors = append(copyErrors, <-errChan)
+	return errorhandling.JoinErrors(copyErrors)
+}
@@ -0,0 +1,192 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	buildahCopiah "github.com/containers/buildah/copier"
+	"github.com/openshift/occ/pkg/engine"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestCopyPortMap(t *testing.T) {
+	type test struct {
+		name            string
+		fileSystemWrite fileSystemWrite
+		container       container
+		copier          copier
+		expected        string
+	}
+
+	tests := []test{
+		{name: "Fails to create temp dir", fileSystemWrite: fsWriteFailMkdirTemp{}, expected: "failed to create a tempdir for portmap: fail"},
+		{name: "Fails to inspect container", fileSystemWrite: fsWriteTest{}, container: containerFailInspect{}, expected: "failed to inspect container: fail"},
+		{name: "Fails to create tmp portmap file", fileSystemWrite: fsWriteFailCreate{}, container: containerTest{}, expected: "failed to create portmap file: fail"},
+		{name: "Fails to write portmap data", fileSystemWrite: fsWriteFailWritePortmap{}, container: containerTest{}, expected: "failed to write host port to portmap file: fail"},
+		{name: "Fails to copy portmap file from host", fileSystemWrite: fsWriteTest{}, container: containerTest{}, copier: copierFailGet{}, expected: "error copying portmap file from host to container: 1 error occurred:\n\t* error copying portmap file from host: fail"},
+		{name: "Fails to copy portmap file to container", fileSystemWrite: fsWriteTest{}, container: containerFailCopyToContainer{}, copier: copierTest{}, expected: "error copying portmap file from host to container: 1 error occurred:\n\t* error copying portmap file to container: fail"},
+		{name: "Successfully copies file from host to container", fileSystemWrite: fsWriteTest{}, container: containerTest{}, copier: copierTest{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := copyPortmap(tc.fileSystemWrite, tc.container, tc.copier, nil, "")
+			if tc.expected == "" && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if tc.expected != "" {
+				if err == nil {
+					t.Fatalf("Expected %v but got no error", tc.expected)
+				}
+				if err.Error() != tc.expected {
+					t.Fatalf("Expected %v but got %v", tc.expected, err)
+				}
+			}
+		})
+	}
+	t.Cleanup(func() {
+		err := os.RemoveAll("foo")
+		if err != nil {
+			t.Fatalf("Failed to clean up sample `foo` file.")
+		}
+	})
+}
+
+func TestDoCopy(t *testing.T) {
+	type test struct {
+		name     string
+		funcA    func() error
+		funcB    func() error
+		expected string
+	}
+
+	tests := []test{
+		{
+			name: "Both functions return errors",
+			funcA: func() error {
+				return errors.New("A")
+			},
+			funcB: func() error {
+				return errors.New("B")
+			},
+			expected: "2 errors occurred:\n\t* B\n\t* A",
+		},
+		{
+			name: "First function returns an error",
+			funcA: func() error {
+				return errors.New("A")
+			},
+			funcB: func() error {
+				return nil
+			},
+			expected: "1 error occurred:\n\t* A",
+		},
+		{
+			name: "Second function returns an error",
+			funcA: func() error {
+				return nil
+			},
+			funcB: func() error {
+				return errors.New("B")
+			},
+			expected: "1 error occurred:\n\t* B",
+		},
+		{
+			name: "Neither function returns an error",
+			funcA: func() error {
+				return nil
+			},
+			funcB: func() error {
+				return nil
+			},
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := doCopy(tc.funcA, tc.funcB)
+			if tc.expected != "" && result.Error() != tc.expected {
+				t.Fatalf("Expected %v but got %v", tc.expected, result)
+			}
+			if tc.expected == "" && result != nil {
+				t.Fatalf("Expected no errors but got %v", result)
+			}
+		})
+	}
+}
+
+// fileSystemWrite impls
+type fsWriteTest struct{}
+
+func (fsWriteTest) RemoveAll(string) error { return nil }
+func (fsWriteTest) Create(string) (*os.File, error) {
+	return os.Create("foo")
+}
+func (fsWriteTest) MkdirTemp(string, string) (string, error)      { return "tmpfile", nil }
+func (fsWriteTest) Fprintln(io.Writer, ...any) (n int, err error) { return 0, nil }
+
+type fsWriteFailMkdirTemp struct{}
+
+func (fsWriteFailMkdirTemp) RemoveAll(string) error                        { panic(nil) }
+func (fsWriteFailMkdirTemp) Create(string) (*os.File, error)               { panic(nil) }
+func (fsWriteFailMkdirTemp) MkdirTemp(string, string) (string, error)      { return "", errors.New("fail") }
+func (fsWriteFailMkdirTemp) Fprintln(io.Writer, ...any) (n int, err error) { panic(nil) }
+
+type fsWriteFailCreate struct{}
+
+func (fsWriteFailCreate) RemoveAll(string) error                        { return nil }
+func (fsWriteFailCreate) Create(string) (*os.File, error)               { return nil, errors.New("fail") }
+func (fsWriteFailCreate) MkdirTemp(string, string) (string, error)      { return "tmpfile", nil }
+func (fsWriteFailCreate) Fprintln(io.Writer, ...any) (n int, err error) { panic(nil) }
+
+type fsWriteFailWritePortmap struct{}
+
+func (fsWriteFailWritePortmap) RemoveAll(string) error                   { return nil }
+func (fsWriteFailWritePortmap) Create(string) (*os.File, error)          { return &os.File{}, nil }
+func (fsWriteFailWritePortmap) MkdirTemp(string, string) (string, error) { return "tmpfile", nil }
+func (fsWriteFailWritePortmap) Fprintln(io.Writer, ...any) (n int, err error) {
+	return 0, errors.New("fail")
+}
+
+// container impls
+type containerTest struct{}
+
+func (containerTest) Inspect(context.Context, string) (*engine.Container, error) {
+	return &engine.Container{Ports: map[string][]string{"9999/tcp": {"12345"}}}, nil
+}
+func (containerTest) CopyToContainer(context.Context, string, string, io.Reader) error {
+	return nil
+}
+
+type containerFailInspect struct{}
+
+func (containerFailInspect) Inspect(context.Context, string) (*engine.Container, error) {
+	return nil, errors.New("fail")
+}
+func (containerFailInspect) CopyToContainer(context.Context, string, string, io.Reader) error {
+	panic(nil)
+}
+
+type containerFailCopyToContainer struct{}
+
+func (containerFailCopyToContainer) Inspect(context.Context, string) (*engine.Container, error) {
+	return &engine.Container{Ports: map[string][]string{"9999/tcp": {"12345"}}}, nil
+}
+func (containerFailCopyToContainer) CopyToContainer(context.Context, string, string, io.Reader) error {
+	return errors.New("fail")
+}
+
+// copier impls
+type copierTest struct{}
+
+func (copierTest) Get(string, string, buildahCopiah.GetOptions, []string, io.Writer) error {
+	return nil
+}
+
+type copierFailGet struct{}
+
+func (copierFailGet) Get(string, string, buildahCopiah.GetOptions, []string, io.Writer) error {
+	return errors.New("fail")
+}
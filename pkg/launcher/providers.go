@@ -0,0 +1,170 @@
+package launcher
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/config"
+)
+
+// Provider is a credential integration, such as a cloud CLI. When any of its Detect paths
+// exists on the host, its mounts and env are added to the session.
+type Provider struct {
+	Name string `mapstructure:"name"`
+	// Enabled defaults to true, set it to false to turn a provider off
+	Enabled *bool `mapstructure:"enabled"`
+	// Detect are host paths, a leading ~/ is expanded to the home directory
+	Detect []string `mapstructure:"detect"`
+	// Mounts are all made once the provider is detected, unless marked optional
+	Mounts []Mount           `mapstructure:"mounts"`
+	Env    map[string]string `mapstructure:"env"`
+}
+
+// IsEnabled reports whether the provider is turned on
+func (p Provider) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// builtinProviders are the integrations occ ships with
+var builtinProviders = []Provider{
+	{
+		Name:   "gcloud",
+		Detect: []string{"~/.config/gcloud"},
+		Mounts: []Mount{
+			{Source: "~/.config/gcloud/active_config", Destination: "/root/.config/gcloud/active_config_readonly"},
+			{Source: "~/.config/gcloud/configurations/config_default", Destination: "/root/.config/gcloud/configurations/config_default_readonly"},
+			{Source: "~/.config/gcloud/credentials.db", Destination: "/root/.config/gcloud/credentials_readonly.db"},
+			{Source: "~/.config/gcloud/access_tokens.db", Destination: "/root/.config/gcloud/access_tokens_readonly.db"},
+		},
+	},
+	{
+		Name:   "aws",
+		Detect: []string{"~/.aws"},
+		Mounts: []Mount{
+			{Source: "~/.aws/credentials", Destination: "/root/.aws/credentials"},
+			{Source: "~/.aws/config", Destination: "/root/.aws/config"},
+		},
+	},
+	{
+		Name:   "pagerduty",
+		Detect: []string{"~/.config/pagerduty-cli/config.json"},
+		Mounts: []Mount{
+			{Source: "~/.config/pagerduty-cli/config.json", Destination: "/root/.config/pagerduty-cli/config.json"},
+		},
+	},
+}
+
+// BuiltinProviders returns the integrations occ ships with
+func BuiltinProviders() []Provider {
+	return append([]Provider{}, builtinProviders...)
+}
+
+// ResolveProviders overlays the configured providers on the built-in ones. A configured provider
+// named after a built-in one replaces it, unless it only sets enabled, which just toggles it.
+func ResolveProviders(configured []Provider) ([]Provider, error) {
+	providers := BuiltinProviders()
+	index := map[string]int{}
+	for i, p := range providers {
+		index[p.Name] = i
+	}
+
+	seen := map[string]bool{}
+	for _, p := range configured {
+		if p.Name == "" {
+			return nil, fmt.Errorf("every provider needs a name")
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("provider %v is defined more than once", p.Name)
+		}
+		seen[p.Name] = true
+
+		toggleOnly := len(p.Detect) == 0 && len(p.Mounts) == 0 && len(p.Env) == 0
+		i, builtin := index[p.Name]
+		switch {
+		case builtin && toggleOnly:
+			providers[i].Enabled = p.Enabled
+		case builtin:
+			providers[i] = p
+		case toggleOnly:
+			return nil, fmt.Errorf("provider %v is not a built-in provider and needs detect and mounts or env", p.Name)
+		default:
+			providers = append(providers, p)
+		}
+	}
+
+	for _, p := range providers {
+		if p.IsEnabled() && len(p.Detect) == 0 {
+			return nil, fmt.Errorf("provider %v needs at least one detect path", p.Name)
+		}
+	}
+	return providers, nil
+}
+
+// activeProviders returns the enabled providers detected on the host
+func activeProviders(fs fileSystemRead, opts Options) ([]Provider, error) {
+	providers, err := ResolveProviders(opts.Providers)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []Provider
+	for _, p := range providers {
+		if p.IsEnabled() && detected(fs, p, opts.HomeDir) {
+			active = append(active, p)
+		}
+	}
+	return active, nil
+}
+
+func detected(fs fileSystemRead, p Provider, homeDir string) bool {
+	for _, detect := range p.Detect {
+		if _, err := fs.Stat(config.ExpandHomeDir(detect, homeDir)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// providerMounts returns the mounts of every active provider. Detecting a provider is
+// enough to make its mounts, only the optional ones are checked for first.
+func providerMounts(fs fileSystemRead, opts Options, destinations map[string]bool) ([]specs.Mount, error) {
+	providers, err := activeProviders(fs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts []specs.Mount
+	for _, p := range providers {
+		for _, m := range p.Mounts {
+			mount, err := bindMount(m, opts.HomeDir, destinations)
+			if err != nil {
+				return nil, fmt.Errorf("provider %v: %v", p.Name, err)
+			}
+			if _, err := fs.Stat(mount.Source); err != nil && m.Optional {
+				continue
+			}
+			destinations[mount.Destination] = true
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts, nil
+}
+
+// providerEnv returns the env of every active provider
+func providerEnv(fs fileSystemRead, opts Options) (map[string]string, error) {
+	providers, err := activeProviders(fs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	envMap := map[string]string{}
+	for _, p := range providers {
+		for name, value := range p.Env {
+			if reservedEnv[name] {
+				return nil, fmt.Errorf("provider %v: env variable %v is set by occ and can't be overridden", p.Name, name)
+			}
+			envMap[name] = value
+		}
+	}
+	return envMap, nil
+}
@@ -0,0 +1,174 @@
+package launcher
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestResolveProviders(t *testing.T) {
+	disabled := false
+	jira := Provider{
+		Name:   "jira",
+		Detect: []string{"~/.config/.jira"},
+		Mounts: []Mount{{Source: "~/.config/.jira", Destination: "/root/.config/.jira"}},
+	}
+
+	type test struct {
+		name          string
+		configured    []Provider
+		expectedNames []string
+		expectedErr   string
+	}
+
+	tests := []test{
+		{
+			name:          "Built-in providers",
+			expectedNames: []string{"gcloud", "aws", "pagerduty"},
+		},
+		{
+			name:          "User-defined provider",
+			configured:    []Provider{jira},
+			expectedNames: []string{"gcloud", "aws", "pagerduty", "jira"},
+		},
+		{
+			name:          "Override a built-in provider",
+			configured:    []Provider{{Name: "aws", Detect: []string{"~/.aws-work"}, Mounts: []Mount{{Source: "~/.aws-work", Destination: "/root/.aws"}}}},
+			expectedNames: []string{"gcloud", "aws", "pagerduty"},
+		},
+		{
+			name:        "Missing name",
+			configured:  []Provider{{Detect: []string{"~/.foo"}}},
+			expectedErr: "needs a name",
+		},
+		{
+			name:        "Defined twice",
+			configured:  []Provider{jira, jira},
+			expectedErr: "more than once",
+		},
+		{
+			name:        "Toggling an unknown provider",
+			configured:  []Provider{{Name: "vault", Enabled: &disabled}},
+			expectedErr: "not a built-in provider",
+		},
+		{
+			name:        "No detect paths",
+			configured:  []Provider{{Name: "vault", Mounts: []Mount{{Source: "~/.vault-token", Destination: "/root/.vault-token"}}}},
+			expectedErr: "at least one detect path",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			providers, err := ResolveProviders(tc.configured)
+
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			var names []string
+			for _, p := range providers {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Fatalf("Expected providers %v, got %v", tc.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestProviderMounts(t *testing.T) {
+	disabled := false
+	testfs := fstest.MapFS{
+		"home_dir/.aws":                  {Mode: fs.ModeDir},
+		"home_dir/.config/.jira":         {Mode: fs.ModeDir},
+		"home_dir/.config/backplane":     {Mode: fs.ModeDir},
+		"home_dir/.config/gcloud":        {Mode: fs.ModeDir},
+		"home_dir/.config/jira-tokens":   {Data: []byte{}},
+		"home_dir/.config/unused/config": {Data: []byte{}},
+	}
+
+	opts := Options{
+		HomeDir: "home_dir",
+		Providers: []Provider{
+			{Name: "gcloud", Enabled: &disabled},
+			{
+				Name:   "jira",
+				Detect: []string{"~/.config/.jira", "~/.jira.d"},
+				Mounts: []Mount{
+					{Source: "~/.config/.jira", Destination: "/root/.config/.jira"},
+					{Source: "~/.config/jira-tokens", Destination: "/root/.config/jira-tokens", Options: []string{"rw"}, Optional: true},
+					{Source: "~/.config/jira-missing", Destination: "/root/.config/jira-missing", Optional: true},
+				},
+				Env: map[string]string{"JIRA_CONFIG": "/root/.config/.jira/config.yml"},
+			},
+			{
+				Name:   "vault",
+				Detect: []string{"~/.vault-token"},
+				Mounts: []Mount{{Source: "~/.vault-token", Destination: "/root/.vault-token"}},
+				Env:    map[string]string{"VAULT_ADDR": "https://vault.example.com"},
+			},
+		},
+	}
+
+	mounts, err := providerMounts(testfs, opts, map[string]bool{})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	expected := []specs.Mount{
+		{Source: "home_dir/.aws/credentials", Destination: "/root/.aws/credentials", Options: []string{"ro"}, Type: define.TypeBind},
+		{Source: "home_dir/.aws/config", Destination: "/root/.aws/config", Options: []string{"ro"}, Type: define.TypeBind},
+		{Source: "home_dir/.config/.jira", Destination: "/root/.config/.jira", Options: []string{"ro"}, Type: define.TypeBind},
+		{Source: "home_dir/.config/jira-tokens", Destination: "/root/.config/jira-tokens", Options: []string{"rw"}, Type: define.TypeBind},
+	}
+	if !reflect.DeepEqual(mounts, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, mounts)
+	}
+
+	env, err := providerEnv(testfs, opts)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !reflect.DeepEqual(env, map[string]string{"JIRA_CONFIG": "/root/.config/.jira/config.yml"}) {
+		t.Fatalf("Unexpected provider env %v", env)
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	testfs := fstest.MapFS{"home_dir/.config/.jira": {Mode: fs.ModeDir}}
+
+	t.Run("mount conflicts with an existing destination", func(t *testing.T) {
+		opts := Options{HomeDir: "home_dir", Providers: []Provider{{
+			Name:   "jira",
+			Detect: []string{"~/.config/.jira"},
+			Mounts: []Mount{{Source: "~/.config/.jira", Destination: "/root/.ssh"}},
+		}}}
+		_, err := providerMounts(testfs, opts, map[string]bool{"/root/.ssh": true})
+		if err == nil || !strings.Contains(err.Error(), "provider jira: mount destination /root/.ssh is already in use") {
+			t.Fatalf("Expected a destination conflict, got %v", err)
+		}
+	})
+
+	t.Run("env overrides a reserved variable", func(t *testing.T) {
+		opts := Options{HomeDir: "home_dir", Providers: []Provider{{
+			Name:   "jira",
+			Detect: []string{"~/.config/.jira"},
+			Env:    map[string]string{"USER": "someone"},
+		}}}
+		_, err := providerEnv(testfs, opts)
+		if err == nil || !strings.Contains(err.Error(), "can't be overridden") {
+			t.Fatalf("Expected a reserved variable error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+	log "github.com/sirupsen/logrus"
+)
+
+// pullImage makes the image available according to the pull policy, and logs the digest sessions will run
+func pullImage(ctx context.Context, eng engine.Engine, ref string, opts Options) error {
+	policy := opts.Pull
+	if policy == "" {
+		policy = image.PullMissing
+	}
+
+	pull := policy == image.PullAlways
+	if !pull {
+		exists, err := eng.ImageExists(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to look for image %v: %v", ref, err)
+		}
+		if !exists && policy == image.PullNever {
+			return fmt.Errorf("%w: %v isn't available locally and the pull policy is %v", ErrImageNotFound, ref, policy)
+		}
+		pull = !exists
+	}
+
+	if pull {
+		log.Infof("Pulling %v", ref)
+		if err := eng.PullImage(ctx, ref, opts.AuthFile, opts.Streams.Stderr); err != nil {
+			return fmt.Errorf("failed to pull %v: %v", ref, err)
+		}
+	}
+
+	digest, err := eng.ImageDigest(ctx, ref)
+	if err != nil {
+		log.Debugf("Unable to resolve the digest of %v: %v", ref, err)
+		return nil
+	}
+	// Printed rather than logged, so it shows without raising the verbosity
+	if opts.Streams.Stderr != nil {
+		fmt.Fprintf(opts.Streams.Stderr, "Using image %v (%v)\n", ref, digest)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package launcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/image"
+)
+
+func TestPullImage(t *testing.T) {
+	type test struct {
+		name           string
+		policy         image.PullPolicy
+		missingImage   bool
+		expectedPulled bool
+		expectedErr    error
+	}
+
+	tests := []test{
+		{name: "missing image is pulled by default", missingImage: true, expectedPulled: true},
+		{name: "present image isn't pulled by default"},
+		{name: "always pulls a present image", policy: image.PullAlways, expectedPulled: true},
+		{name: "never pulls a missing image", policy: image.PullNever, missingImage: true, expectedErr: ErrImageNotFound},
+		{name: "never uses a present image", policy: image.PullNever},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			eng := &fakeEngine{missingImage: tc.missingImage}
+			var stderr bytes.Buffer
+			err := pullImage(context.Background(), eng, DefaultImage, Options{Pull: tc.policy, Streams: engine.Streams{Stderr: &stderr}})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if pulled := len(eng.pulled) > 0; pulled != tc.expectedPulled {
+				t.Fatalf("Expected pulled to be %v, got %v", tc.expectedPulled, eng.pulled)
+			}
+			if printed := strings.Contains(stderr.String(), "sha256:test"); printed != (tc.expectedErr == nil) {
+				t.Fatalf("Expected the digest to be printed only when the image is available, got %q", stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package launcher
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/recording"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+const (
+	// defaultWidth and defaultHeight size recordings of sessions without a terminal
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// startRecording tees the session's streams into a new recording, when opts.Record.Dir is set.
+// It returns the path of the recording, and a func that ends it once the session is over.
+func startRecording(spec engine.Spec, containerID string, opts Options, streams *engine.Streams) (string, func(), error) {
+	if opts.Record.Dir == "" {
+		return "", func() {}, nil
+	}
+
+	start := time.Now()
+	path := recording.Path(opts.Record.Dir, spec.Name, containerID, start)
+	f, err := recording.Create(path)
+	if err != nil {
+		return "", nil, err
+	}
+	width, height := terminalSize(streams.Stdout)
+	header := recording.Header{Width: width, Height: height, Timestamp: start.Unix(), Title: spec.Name}
+	if term := os.Getenv("TERM"); term != "" {
+		header.Env = map[string]string{"TERM": term}
+	}
+	rec, err := recording.NewRecorder(f, header)
+	if err != nil {
+		f.Close()
+		return "", nil, err
+	}
+
+	streams.Tee = rec.Output()
+	if opts.Record.Input {
+		streams.TeeInput = rec.Input()
+	}
+	log.Debugf("Recording session %v to %v", spec.Name, path)
+	return path, func() {
+		if err := rec.Close(); err != nil {
+			log.Warnf("Unable to save the recording of session %v: %v", spec.Name, err)
+		}
+	}, nil
+}
+
+// terminalSize returns the size of the terminal the session is shown on, or the default size without one
+func terminalSize(out io.Writer) (int, int) {
+	if f, ok := out.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if width, height, err := term.GetSize(int(f.Fd())); err == nil {
+			return width, height
+		}
+	}
+	return defaultWidth, defaultHeight
+}
@@ -0,0 +1,37 @@
+package launcher
+
+import (
+	"strings"
+
+	"github.com/openshift/occ/pkg/engine"
+)
+
+// Redacted replaces the value of secret environment variables in Redact
+const Redacted = "<redacted>"
+
+// secretEnvMarkers are the parts of an environment variable name that mark its value as a secret
+var secretEnvMarkers = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "PRIVATE_KEY", "API_KEY", "ACCESS_KEY"}
+
+// IsSecretEnv reports whether the value of the named environment variable should never be printed
+func IsSecretEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range secretEnvMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns a copy of the spec that is safe to print, with the value of every secret environment variable replaced
+func Redact(spec engine.Spec) engine.Spec {
+	env := make(map[string]string, len(spec.Env))
+	for k, v := range spec.Env {
+		if IsSecretEnv(k) && v != "" {
+			v = Redacted
+		}
+		env[k] = v
+	}
+	spec.Env = env
+	return spec
+}
@@ -0,0 +1,41 @@
+package launcher
+
+import (
+	"testing"
+
+	"github.com/openshift/occ/pkg/engine"
+)
+
+func TestRedact(t *testing.T) {
+	spec := engine.Spec{
+		Image: "image",
+		Env: map[string]string{
+			"OFFLINE_ACCESS_TOKEN":  "token",
+			"AWS_SECRET_ACCESS_KEY": "secret",
+			"db_password":           "password",
+			"EMPTY_TOKEN":           "",
+			"USER":                  "testUser",
+			"SSH_AUTH_SOCK":         "/tmp/ssh.sock",
+		},
+	}
+
+	redacted := Redact(spec)
+
+	expected := map[string]string{
+		"OFFLINE_ACCESS_TOKEN":  Redacted,
+		"AWS_SECRET_ACCESS_KEY": Redacted,
+		"db_password":           Redacted,
+		"EMPTY_TOKEN":           "",
+		"USER":                  "testUser",
+		"SSH_AUTH_SOCK":         "/tmp/ssh.sock",
+	}
+	for k, v := range expected {
+		if redacted.Env[k] != v {
+			t.Errorf("Expected %v to be %q, got %q", k, v, redacted.Env[k])
+		}
+	}
+
+	if spec.Env["OFFLINE_ACCESS_TOKEN"] != "token" {
+		t.Errorf("Expected the original spec to be left alone")
+	}
+}
@@ -0,0 +1,147 @@
+package launcher
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// RemoteMountWarnings lists the bind mounts of the spec that can't be carried over to a remote engine
+func RemoteMountWarnings(spec engine.Spec) []string {
+	_, warnings := remoteMounts(&spec)
+	return warnings
+}
+
+// remoteMounts takes the bind mounts out of the spec, as their sources only exist on this host,
+// and returns the ones that can be copied into the container instead.
+// Sockets can't be copied, and changes to copied rw mounts aren't written back, which is warned about.
+func remoteMounts(spec *engine.Spec) ([]specs.Mount, []string) {
+	var kept, copies []specs.Mount
+	var warnings []string
+	for _, m := range spec.Mounts {
+		if m.Type != define.TypeBind {
+			kept = append(kept, m)
+			continue
+		}
+
+		info, err := os.Stat(m.Source)
+		switch {
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("Not copying %v to the remote session: %v", m.Source, err))
+		case info.Mode()&fs.ModeSocket != 0:
+			warnings = append(warnings, fmt.Sprintf("%v is a socket, which is local-only and can't be used by a remote session", m.Source))
+		default:
+			if hasOption(m.Options, "rw") {
+				warnings = append(warnings, fmt.Sprintf("%v is copied to the remote session, changes to %v won't be written back", m.Source, m.Destination))
+			}
+			copies = append(copies, m)
+		}
+	}
+	spec.Mounts = kept
+	return copies, warnings
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// copyMounts copies the source of each mount to its destination in the container
+func copyMounts(ctx context.Context, container container, containerID string, mounts []specs.Mount) error {
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	hostCopy := func() error {
+		err := writeMountsArchive(writer, mounts)
+		writer.CloseWithError(err)
+		return err
+	}
+	containerCopy := func() error {
+		defer reader.Close()
+		return container.CopyToContainer(ctx, containerID, "/", reader)
+	}
+	return doCopy(hostCopy, containerCopy)
+}
+
+// writeMountsArchive writes a tar archive holding each mount source at its destination, relative to /
+func writeMountsArchive(w io.Writer, mounts []specs.Mount) error {
+	tw := tar.NewWriter(w)
+	for _, m := range mounts {
+		if err := addToArchive(tw, m.Source, m.Destination); err != nil {
+			return fmt.Errorf("failed to copy %v: %v", m.Source, err)
+		}
+	}
+	return tw.Close()
+}
+
+func addToArchive(tw *tar.Writer, source string, destination string) error {
+	// The source itself may be a symlink, such as a dotfile managed elsewhere, so it's followed
+	root, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case info.Mode().IsRegular(), info.IsDir():
+		case info.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		default:
+			// ssh control sockets and the like only mean something on this host
+			log.Debugf("Not copying %v to the remote session, it isn't a regular file", path)
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = strings.TrimPrefix(filepath.ToSlash(filepath.Join(destination, rel)), "/")
+		// The files belong to root in the container, not to the host user
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
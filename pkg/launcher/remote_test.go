@@ -0,0 +1,115 @@
+package launcher
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/engine"
+)
+
+func TestRemoteMounts(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on test socket: %v", err)
+	}
+	defer l.Close()
+
+	spec := engine.Spec{Mounts: []specs.Mount{
+		{Destination: "/root/.ssh/sockets", Type: define.TypeTmpfs},
+		{Source: dir, Destination: "/root/.aws", Options: []string{"ro"}, Type: define.TypeBind},
+		{Source: dir, Destination: "/root/sop-utils", Options: []string{"rw"}, Type: define.TypeBind},
+		{Source: socket, Destination: "/tmp/ssh.sock", Options: []string{"ro"}, Type: define.TypeBind},
+		{Source: filepath.Join(dir, "missing"), Destination: "/root/missing", Type: define.TypeBind},
+	}}
+
+	copies, warnings := remoteMounts(&spec)
+
+	if len(spec.Mounts) != 1 || spec.Mounts[0].Type != define.TypeTmpfs {
+		t.Fatalf("Expected only the tmpfs mount to be kept, got %+v", spec.Mounts)
+	}
+	if len(copies) != 2 || copies[0].Destination != "/root/.aws" || copies[1].Destination != "/root/sop-utils" {
+		t.Fatalf("Expected the directories to be copied, got %+v", copies)
+	}
+	expected := []string{"won't be written back", "is a socket", "Not copying"}
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expected %v warnings, got %v", len(expected), warnings)
+	}
+	for i, w := range warnings {
+		if !strings.Contains(w, expected[i]) {
+			t.Fatalf("Expected warning %v to contain %q, got %q", i, expected[i], w)
+		}
+	}
+}
+
+func TestWriteMountsArchive(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "ssh", "sockets"), 0700); err != nil {
+		t.Fatalf("Failed to create test dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ssh", "config"), []byte("Host *"), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.Symlink("config", filepath.Join(dir, "ssh", "config.link")); err != nil {
+		t.Fatalf("Failed to create test symlink: %v", err)
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "ssh", "sockets", "control"))
+	if err != nil {
+		t.Fatalf("Failed to listen on test socket: %v", err)
+	}
+	defer l.Close()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("ocm_user: test"), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	mounts := []specs.Mount{
+		{Source: filepath.Join(dir, "ssh"), Destination: "/root/.ssh"},
+		{Source: filepath.Join(dir, "config.yaml"), Destination: "/root/.config/occ"},
+	}
+	var buf bytes.Buffer
+	if err := writeMountsArchive(&buf, mounts); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	contents := map[string]string{}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		contents[hdr.Name] = string(data) + hdr.Linkname
+		if hdr.Uid != 0 || hdr.Gid != 0 {
+			t.Fatalf("Expected %v to belong to root, got %v:%v", hdr.Name, hdr.Uid, hdr.Gid)
+		}
+	}
+
+	expected := map[string]string{
+		"root/.ssh":             "",
+		"root/.ssh/config":      "Host *",
+		"root/.ssh/config.link": "config",
+		"root/.ssh/sockets":     "",
+		"root/.config/occ":      "ocm_user: test",
+	}
+	if len(contents) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, contents)
+	}
+	for name, data := range expected {
+		if got, ok := contents[name]; !ok || got != data {
+			t.Fatalf("Expected %v to hold %q, got %q", name, data, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openshift/occ/pkg/engine"
+	"github.com/openshift/occ/pkg/session"
+	log "github.com/sirupsen/logrus"
+)
+
+// moveSecrets replaces the secret env variables of the spec with engine secrets, so their values
+// don't show up when the container is inspected. Engines without secrets keep them as plain env.
+func moveSecrets(ctx context.Context, eng engine.Engine, spec *engine.Spec) error {
+	names := secretEnvNames(*spec)
+	for _, name := range names {
+		secretName := session.SecretName(spec.Name, name)
+		// A session by this name doesn't exist, so anything left under the secret's name is stale
+		if err := eng.RemoveSecret(ctx, secretName); err != nil {
+			return fmt.Errorf("failed to remove stale secret %v: %v", secretName, err)
+		}
+
+		err := eng.CreateSecret(ctx, secretName, []byte(spec.Env[name]), session.SecretLabels(spec.Name))
+		if errors.Is(err, engine.ErrSecretsUnsupported) {
+			log.Warnf("Passing %v as plain env variables, readable by anyone who can inspect the container: %v", strings.Join(names, ", "), err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create secret for %v: %v", name, err)
+		}
+
+		useSecret(spec, name, secretName)
+	}
+	return nil
+}
+
+// secretEnvNames returns the sorted names of the env variables of the spec that are passed as secrets
+func secretEnvNames(spec engine.Spec) []string {
+	var names []string
+	for name, value := range spec.Env {
+		if IsSecretEnv(name) && value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// useSecret passes the env variable to the session through the named engine secret rather than plain env
+func useSecret(spec *engine.Spec, name string, secretName string) {
+	if spec.SecretEnv == nil {
+		spec.SecretEnv = map[string]string{}
+	}
+	spec.SecretEnv[name] = secretName
+	delete(spec.Env, name)
+}
+
+// removeSecrets cleans up the session's secrets once it's over
+func removeSecrets(ctx context.Context, eng engine.Engine, sessionName string) {
+	if err := session.RemoveSecrets(ctx, eng, sessionName); err != nil {
+		log.Warnf("Unable to clean up after session %v: %v", sessionName, err)
+	}
+}
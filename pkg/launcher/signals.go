@@ -0,0 +1,83 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/openshift/occ/pkg/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// StopTimeout is how many seconds a session gets to exit when occ is terminated, before it's killed
+const StopTimeout = 10
+
+// Signals are closed as occ receives signals during a session
+type Signals struct {
+	// HangUp is closed when the terminal hangs up, the session is left running to be reattached
+	HangUp <-chan struct{}
+	// Terminated is closed when occ is interrupted or terminated, once it started stopping the session
+	Terminated <-chan struct{}
+}
+
+// NotifySignals stops the container when occ is interrupted or terminated, so the session doesn't
+// outlive it unattended. Call stop once the session has ended.
+func NotifySignals(ctx context.Context, eng engine.Engine, nameOrID string) (signals Signals, stop func()) {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	ctx, cancel := context.WithCancel(ctx)
+	signals = handleSignals(ctx, eng, nameOrID, received)
+	return signals, func() {
+		signal.Stop(received)
+		cancel()
+	}
+}
+
+// handleSignals stops the container on every signal but a hang up. The first one gives it
+// StopTimeout seconds to exit, any later one kills it straight away.
+func handleSignals(ctx context.Context, eng engine.Engine, nameOrID string, received <-chan os.Signal) Signals {
+	hangUp := make(chan struct{})
+	terminated := make(chan struct{})
+	go func() {
+		timeout := uint(StopTimeout)
+		hungUp := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-received:
+				if sig == syscall.SIGHUP {
+					// Keep handling signals, so the session can still be stopped while occ detaches from it
+					if !hungUp {
+						close(hangUp)
+						hungUp = true
+					}
+					continue
+				}
+				log.Warnf("Received %v, stopping the session", sig)
+				go func(timeout uint) {
+					if err := eng.Stop(ctx, nameOrID, timeout); err != nil {
+						log.Errorf("Unable to stop the session: %v", err)
+					}
+				}(timeout)
+				if timeout != 0 {
+					close(terminated)
+				}
+				timeout = 0
+			}
+		}
+	}()
+	return Signals{HangUp: hangUp, Terminated: terminated}
+}
+
+// interrupted returns ErrInterrupted once occ was interrupted or terminated before the session started
+func interrupted(signals Signals) error {
+	select {
+	case <-signals.Terminated:
+		return fmt.Errorf("%w before the session started", ErrInterrupted)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package launcher
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/openshift/occ/pkg/engine"
+)
+
+// stopEngine reports the timeout of every Stop call
+type stopEngine struct {
+	engine.Engine
+	stopped chan uint
+}
+
+func (s *stopEngine) Stop(_ context.Context, _ string, timeout uint) error {
+	s.stopped <- timeout
+	return nil
+}
+
+func TestHandleSignals(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eng := &stopEngine{stopped: make(chan uint)}
+	signals := make(chan os.Signal)
+	received := handleSignals(ctx, eng, "test-id", signals)
+
+	signals <- syscall.SIGTERM
+	if timeout := <-eng.stopped; timeout != StopTimeout {
+		t.Fatalf("Expected the first signal to stop the session within %v seconds, got %v", StopTimeout, timeout)
+	}
+	<-received.Terminated
+	signals <- os.Interrupt
+	if timeout := <-eng.stopped; timeout != 0 {
+		t.Fatalf("Expected a second signal to kill the session, got a timeout of %v", timeout)
+	}
+
+	signals <- syscall.SIGHUP
+	<-received.HangUp
+}
+
+func TestHandleSignalsAfterHangUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eng := &stopEngine{stopped: make(chan uint)}
+	signals := make(chan os.Signal)
+	received := handleSignals(ctx, eng, "test-id", signals)
+
+	signals <- syscall.SIGHUP
+	<-received.HangUp
+	signals <- syscall.SIGHUP
+	signals <- os.Interrupt
+	if timeout := <-eng.stopped; timeout != StopTimeout {
+		t.Fatalf("Expected a signal after a hang up to stop the session within %v seconds, got %v", StopTimeout, timeout)
+	}
+	<-received.Terminated
+}
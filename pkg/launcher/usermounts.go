@@ -0,0 +1,102 @@
+package launcher
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/openshift/occ/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Mount is an extra host path to mount into every session, declared under mounts in the occ config
+type Mount struct {
+	// Source is the host path. A leading ~/ is expanded to the home directory.
+	Source      string `mapstructure:"source"`
+	Destination string `mapstructure:"destination"`
+	// Options are passed to the engine as is, the mount is read-only unless they contain rw
+	Options []string `mapstructure:"options"`
+	// Optional skips the mount when Source doesn't exist, rather than failing the launch
+	Optional bool `mapstructure:"optional"`
+}
+
+// userMounts checks the configured mounts and converts them, refusing any that would
+// shadow a mount occ already makes
+func userMounts(fs fileSystemRead, opts Options, destinations map[string]bool) ([]specs.Mount, error) {
+	var mounts []specs.Mount
+	for _, m := range opts.Mounts {
+		mount, err := bindMount(m, opts.HomeDir, destinations)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := fs.Stat(mount.Source); err != nil {
+			if m.Optional {
+				log.Debugf("Skipping optional mount %v, %v doesn't exist", mount.Destination, mount.Source)
+				continue
+			}
+			return nil, fmt.Errorf("mount source %v for %v doesn't exist", mount.Source, mount.Destination)
+		}
+
+		destinations[mount.Destination] = true
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// bindMount validates a mount against the destinations already in use and converts it
+func bindMount(m Mount, homeDir string, destinations map[string]bool) (specs.Mount, error) {
+	if m.Source == "" || m.Destination == "" {
+		return specs.Mount{}, fmt.Errorf("mount %v:%v needs both a source and a destination", m.Source, m.Destination)
+	}
+
+	destination := path.Clean(m.Destination)
+	if !path.IsAbs(destination) {
+		return specs.Mount{}, fmt.Errorf("mount destination %v must be an absolute path", m.Destination)
+	}
+	if destinations[destination] {
+		return specs.Mount{}, fmt.Errorf("mount destination %v is already in use", destination)
+	}
+
+	options, err := mountOptions(m.Options)
+	if err != nil {
+		return specs.Mount{}, fmt.Errorf("mount %v: %v", destination, err)
+	}
+
+	return specs.Mount{
+		Source:      config.ExpandHomeDir(m.Source, homeDir),
+		Destination: destination,
+		Options:     options,
+		Type:        define.TypeBind,
+	}, nil
+}
+
+// mountDestinations returns the set of destinations the mounts use
+func mountDestinations(mounts []specs.Mount) map[string]bool {
+	destinations := map[string]bool{}
+	for _, m := range mounts {
+		destinations[path.Clean(m.Destination)] = true
+	}
+	return destinations
+}
+
+// mountOptions makes mounts read-only unless rw is asked for explicitly
+func mountOptions(options []string) ([]string, error) {
+	var ro, rw bool
+	for _, option := range options {
+		switch option {
+		case "ro":
+			ro = true
+		case "rw":
+			rw = true
+		}
+	}
+	if ro && rw {
+		return nil, fmt.Errorf("options can't contain both ro and rw")
+	}
+	if ro || rw {
+		return options, nil
+	}
+	return append([]string{"ro"}, options...), nil
+}
@@ -0,0 +1,115 @@
+package launcher
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/containers/podman/v4/libpod/define"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestUserMounts(t *testing.T) {
+	testfs := fstest.MapFS{
+		"home_dir/scripts": {Mode: fs.ModeDir},
+		"shared/notes":     {Mode: fs.ModeDir},
+	}
+	existing := []specs.Mount{{Destination: "/root/.ssh"}}
+
+	type test struct {
+		name           string
+		mounts         []Mount
+		expectedMounts []specs.Mount
+		expectedErr    string
+	}
+
+	tests := []test{
+		{
+			name:   "No mounts",
+			mounts: nil,
+		},
+		{
+			name: "Read-only by default with home expanded",
+			mounts: []Mount{
+				{Source: "~/scripts", Destination: "/root/scripts"},
+			},
+			expectedMounts: []specs.Mount{
+				{Source: "home_dir/scripts", Destination: "/root/scripts", Options: []string{"ro"}, Type: define.TypeBind},
+			},
+		},
+		{
+			name: "Read-write with extra options",
+			mounts: []Mount{
+				{Source: "shared/notes", Destination: "/root/notes/", Options: []string{"rw", "z"}},
+			},
+			expectedMounts: []specs.Mount{
+				{Source: "shared/notes", Destination: "/root/notes", Options: []string{"rw", "z"}, Type: define.TypeBind},
+			},
+		},
+		{
+			name: "Optional missing source is skipped",
+			mounts: []Mount{
+				{Source: "missing", Destination: "/root/missing", Optional: true},
+				{Source: "shared/notes", Destination: "/root/notes"},
+			},
+			expectedMounts: []specs.Mount{
+				{Source: "shared/notes", Destination: "/root/notes", Options: []string{"ro"}, Type: define.TypeBind},
+			},
+		},
+		{
+			name:        "Required missing source",
+			mounts:      []Mount{{Source: "missing", Destination: "/root/missing"}},
+			expectedErr: "doesn't exist",
+		},
+		{
+			name:        "Missing destination",
+			mounts:      []Mount{{Source: "shared/notes"}},
+			expectedErr: "needs both a source and a destination",
+		},
+		{
+			name:        "Relative destination",
+			mounts:      []Mount{{Source: "shared/notes", Destination: "notes"}},
+			expectedErr: "must be an absolute path",
+		},
+		{
+			name:        "Shadows a built-in mount",
+			mounts:      []Mount{{Source: "shared/notes", Destination: "/root/.ssh/"}},
+			expectedErr: "already in use",
+		},
+		{
+			name: "Duplicate destination",
+			mounts: []Mount{
+				{Source: "shared/notes", Destination: "/root/notes"},
+				{Source: "~/scripts", Destination: "/root/notes"},
+			},
+			expectedErr: "already in use",
+		},
+		{
+			name:        "Both ro and rw",
+			mounts:      []Mount{{Source: "shared/notes", Destination: "/root/notes", Options: []string{"ro", "rw"}}},
+			expectedErr: "both ro and rw",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{HomeDir: "home_dir", Mounts: tc.mounts}
+			mounts, err := userMounts(testfs, opts, mountDestinations(existing))
+
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if !reflect.DeepEqual(mounts, tc.expectedMounts) {
+				t.Fatalf("Expected %+v, got %+v", tc.expectedMounts, mounts)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/occ/pkg/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// verifyImage checks the signature of the image the session runs, when verification is configured.
+// The customized image is built locally, so its base image is verified instead.
+func verifyImage(ctx context.Context, eng engine.Engine, ref string, opts Options) error {
+	if !opts.Verify.Enabled() {
+		return nil
+	}
+	if opts.SkipVerify {
+		log.Warnf("Not verifying the signature of %v", ref)
+		return nil
+	}
+
+	digest, err := eng.ImageDigest(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the digest of %v: %v", ref, err)
+	}
+	if err := opts.Verify.Verify(ctx, ref, digest, opts.AuthFile); err != nil {
+		return fmt.Errorf("%w for %v (%v): %v", ErrSignature, ref, digest, err)
+	}
+	log.Infof("Verified the signature of %v (%v)", ref, digest)
+	return nil
+}
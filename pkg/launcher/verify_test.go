@@ -0,0 +1,33 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openshift/occ/pkg/image"
+)
+
+func TestVerifyImage(t *testing.T) {
+	missingKey := image.Verification{Key: "/nonexistent/cosign.pub", KeyType: image.KeyTypeSigstore}
+	tests := []struct {
+		name        string
+		image       string
+		opts        Options
+		expectedErr error
+	}{
+		{name: "not configured", image: DefaultImage},
+		{name: "skipped", image: DefaultImage, opts: Options{Verify: missingKey, SkipVerify: true}},
+		{name: "local image", image: DefaultImage, opts: Options{Verify: missingKey}, expectedErr: ErrSignature},
+		{name: "missing key", image: "quay.io/app-sre/ocm-container:latest", opts: Options{Verify: missingKey}, expectedErr: ErrSignature},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyImage(context.Background(), &fakeEngine{}, tc.image, tc.opts)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Version is the asciicast format version recordings are written in
+	Version = 2
+
+	// EventOutput is written to the terminal, EventInput is typed by the user
+	EventOutput = "o"
+	EventInput  = "i"
+)
+
+// Header is the first line of an asciicast v2 file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes the events of a session to an asciicast v2 file.
+// It's safe to write the output and input events from several goroutines.
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	now   func() time.Time
+	// pending holds the start of a character split across writes, per event type
+	pending map[string][]byte
+	// failed is set once a write fails or the recorder is closed
+	failed bool
+}
+
+// NewRecorder writes the header to w, and returns a recorder timing events from now on
+func NewRecorder(w io.Writer, header Header) (*Recorder, error) {
+	return newRecorder(w, header, time.Now)
+}
+
+func newRecorder(w io.Writer, header Header, now func() time.Time) (*Recorder, error) {
+	header.Version = Version
+	line, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write the recording header: %v", err)
+	}
+	return &Recorder{w: w, start: now(), now: now, pending: map[string][]byte{}}, nil
+}
+
+// Output returns a writer recording what's written to it as terminal output
+func (r *Recorder) Output() io.Writer { return eventWriter{r, EventOutput} }
+
+// Input returns a writer recording what's written to it as user input
+func (r *Recorder) Input() io.Writer { return eventWriter{r, EventInput} }
+
+// Close stops recording, dropping anything written afterwards, and closes the file it records to
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = true
+	if c, ok := r.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type eventWriter struct {
+	r    *Recorder
+	kind string
+}
+
+// Write never fails, so a recording problem can't break the session it records
+func (w eventWriter) Write(p []byte) (int, error) {
+	w.r.event(w.kind, p)
+	return len(p), nil
+}
+
+func (r *Recorder) event(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failed {
+		return
+	}
+
+	data := append(r.pending[kind], p...)
+	n := completeRunes(data)
+	r.pending[kind] = append([]byte(nil), data[n:]...)
+	if n == 0 {
+		return
+	}
+
+	line, err := json.Marshal([]interface{}{r.now().Sub(r.start).Seconds(), kind, string(data[:n])})
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		r.failed = true
+		log.Warnf("Unable to record the session, the rest of it won't be recorded: %v", err)
+	}
+}
+
+// completeRunes returns the length of data without a UTF-8 character cut off at its end,
+// since events are JSON strings and a split character would be replaced
+func completeRunes(data []byte) int {
+	n := len(data)
+	for i := 1; i <= utf8.UTFMax && i <= n; i++ {
+		if utf8.RuneStart(data[n-i]) {
+			if !utf8.FullRune(data[n-i:]) {
+				return n - i
+			}
+			break
+		}
+	}
+	return n
+}
+
+// Play writes the output of the recording read from r to out, with the delays it was recorded with
+// divided by speed. Input events aren't played, as the terminal echoed them as output already.
+func Play(ctx context.Context, r io.Reader, out io.Writer, speed float64) error {
+	return play(ctx, r, out, speed, wait)
+}
+
+func play(ctx context.Context, r io.Reader, out io.Writer, speed float64, wait func(context.Context, time.Duration) error) error {
+	if speed <= 0 {
+		return fmt.Errorf("speed must be greater than 0, got %v", speed)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read the recording: %v", err)
+		}
+		return fmt.Errorf("empty recording")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid recording header: %v", err)
+	}
+	if header.Version != Version {
+		return fmt.Errorf("unsupported asciicast version %v, expected %v", header.Version, Version)
+	}
+
+	var last float64
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("invalid recording event on line %d: %v", line, err)
+		}
+		if len(event) != 3 {
+			return fmt.Errorf("invalid recording event on line %d", line)
+		}
+		at, ok := event[0].(float64)
+		if !ok {
+			return fmt.Errorf("invalid recording event on line %d", line)
+		}
+		kind, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if kind != EventOutput {
+			continue
+		}
+
+		if at > last {
+			if err := wait(ctx, time.Duration((at-last)/speed*float64(time.Second))); err != nil {
+				return err
+			}
+			last = at
+		}
+		if _, err := io.WriteString(out, data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the recording: %v", err)
+	}
+	return nil
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	var out bytes.Buffer
+	rec, err := newRecorder(&out, Header{Width: 120, Height: 40, Title: "occ-1234"}, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	now = start.Add(500 * time.Millisecond)
+	_, _ = rec.Output().Write([]byte("$ "))
+	now = start.Add(time.Second)
+	_, _ = rec.Input().Write([]byte("ls\r"))
+	// é split across two writes is recorded whole, with the second write
+	_, _ = rec.Output().Write([]byte("caf\xc3"))
+	now = start.Add(2 * time.Second)
+	_, _ = rec.Output().Write([]byte("\xa9\r\n"))
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	_, _ = rec.Output().Write([]byte("after close"))
+
+	expected := `{"version":2,"width":120,"height":40,"title":"occ-1234"}
+[0.5,"o","$ "]
+[1,"i","ls\r"]
+[1,"o","caf"]
+[2,"o","é\r\n"]
+`
+	if out.String() != expected {
+		t.Fatalf("Expected recording:\n%v\ngot:\n%v", expected, out.String())
+	}
+}
+
+func TestPlay(t *testing.T) {
+	cast := `{"version":2,"width":80,"height":24}
+[0.5,"o","$ "]
+[1,"i","ls\r"]
+[1.5,"o","ls\r\n"]
+
+[1.5,"o","file\r\n"]
+`
+	tests := []struct {
+		name          string
+		cast          string
+		speed         float64
+		expectedOut   string
+		expectedWaits []time.Duration
+		expectErr     bool
+	}{
+		{name: "normal speed", cast: cast, speed: 1, expectedOut: "$ ls\r\nfile\r\n", expectedWaits: []time.Duration{500 * time.Millisecond, time.Second}},
+		{name: "twice as fast", cast: cast, speed: 2, expectedOut: "$ ls\r\nfile\r\n", expectedWaits: []time.Duration{250 * time.Millisecond, 500 * time.Millisecond}},
+		{name: "invalid speed", cast: cast, speed: 0, expectErr: true},
+		{name: "empty", cast: "", speed: 1, expectErr: true},
+		{name: "unsupported version", cast: `{"version":1}`, speed: 1, expectErr: true},
+		{name: "invalid event", cast: `{"version":2}` + "\n[0.5,\"o\"]\n", speed: 1, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var waits []time.Duration
+			err := play(context.Background(), strings.NewReader(tc.cast), &out, tc.speed, func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			})
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if out.String() != tc.expectedOut {
+				t.Fatalf("Expected output %q, got %q", tc.expectedOut, out.String())
+			}
+			if len(waits) != len(tc.expectedWaits) {
+				t.Fatalf("Expected waits %v, got %v", tc.expectedWaits, waits)
+			}
+			for i := range waits {
+				if waits[i] != tc.expectedWaits[i] {
+					t.Fatalf("Expected waits %v, got %v", tc.expectedWaits, waits)
+				}
+			}
+		})
+	}
+}
+
+func TestPlayCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Play(ctx, strings.NewReader(`{"version":2}`+"\n[5,\"o\",\"late\"]\n"), &bytes.Buffer{}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected playback to be canceled, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/occ/pkg/config"
+	"github.com/openshift/occ/pkg/session"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Key turns on recording every session, InputKey records what's typed in them too
+	Key      = "record"
+	InputKey = "record_input"
+	// DirKey is where recordings are stored, which defaults to DefaultDir in the data directory
+	DirKey = "recording_dir"
+
+	// DefaultDir is the name of the recordings directory in the data directory
+	DefaultDir = "recordings"
+	// Extension ends the name of every recording
+	Extension = ".cast"
+
+	timeLayout = "20060102T150405Z"
+	idLength   = 12
+)
+
+// ErrNotFound is returned when no recording matches a session
+var ErrNotFound = errors.New("no recording found")
+
+// Options configures session recording
+type Options struct {
+	// Dir is where recordings are stored, in a directory per session. Nothing is recorded when it's empty.
+	Dir string
+	// Input records what the user types as well as the session's output
+	Input bool
+}
+
+// FromConfig returns the recording options from the config, which only set Dir when recording is on
+func FromConfig(v *viper.Viper, dataDir string) Options {
+	if !v.GetBool(Key) {
+		return Options{}
+	}
+	return Options{Dir: DirFromConfig(v, dataDir), Input: v.GetBool(InputKey)}
+}
+
+// DirFromConfig returns where recordings are stored, which defaults to DefaultDir in dataDir
+func DirFromConfig(v *viper.Viper, dataDir string) string {
+	dir := v.GetString(DirKey)
+	if dir == "" {
+		return filepath.Join(dataDir, DefaultDir)
+	}
+	return config.ExpandHome(dir)
+}
+
+// Path returns where the recording of a session started at start is stored.
+// Recordings sort by start time within the directory of their session.
+func Path(dir string, sessionName string, containerID string, start time.Time) string {
+	if len(containerID) > idLength {
+		containerID = containerID[:idLength]
+	}
+	name := start.UTC().Format(timeLayout) + "_" + containerID + Extension
+	return filepath.Join(dir, sessionName, name)
+}
+
+// Create creates the file to record a session to. Sessions print credentials, so only its owner can read it.
+func Create(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the recordings directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the recording: %v", err)
+	}
+	return f, nil
+}
+
+// Find returns the recording of the session with the given ID, or the latest recording of the
+// session with the given name or cluster ID. A path to a recording is returned as is.
+func Find(dir string, session string) (string, error) {
+	if info, err := os.Stat(session); err == nil && !info.IsDir() {
+		return session, nil
+	}
+
+	for _, name := range sessionNames(session) {
+		recordings, _ := filepath.Glob(filepath.Join(dir, name, "*"+Extension))
+		if len(recordings) > 0 {
+			sort.Strings(recordings)
+			return recordings[len(recordings)-1], nil
+		}
+	}
+
+	id := session
+	if len(id) > idLength {
+		id = id[:idLength]
+	}
+	recordings, _ := filepath.Glob(filepath.Join(dir, "*", "*_"+id+"*"+Extension))
+	switch len(recordings) {
+	case 0:
+		return "", fmt.Errorf("%w for %v in %v", ErrNotFound, session, dir)
+	case 1:
+		return recordings[0], nil
+	default:
+		return "", fmt.Errorf("multiple recordings found for %v, use a longer session ID", session)
+	}
+}
+
+// sessionNames are the session names a session name or cluster ID may refer to
+func sessionNames(nameOrCluster string) []string {
+	if strings.HasPrefix(nameOrCluster, session.NamePrefix) {
+		return []string{nameOrCluster}
+	}
+	return []string{nameOrCluster, session.Name(nameOrCluster)}
+}
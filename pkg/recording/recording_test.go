@@ -0,0 +1,84 @@
+package recording
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestFromConfig(t *testing.T) {
+	v := viper.New()
+	if opts := FromConfig(v, "/data"); opts.Dir != "" {
+		t.Fatalf("Expected recording to be off by default, got %+v", opts)
+	}
+	v.Set(Key, true)
+	if opts := FromConfig(v, "/data"); opts.Dir != "/data/recordings" || opts.Input {
+		t.Fatalf("Expected output to be recorded to the data directory, got %+v", opts)
+	}
+	v.Set(DirKey, "/recordings")
+	v.Set(InputKey, true)
+	if opts := FromConfig(v, "/data"); opts.Dir != "/recordings" || !opts.Input {
+		t.Fatalf("Expected input to be recorded to the configured directory, got %+v", opts)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	older := Path(dir, "occ-1234", "aaaaaaaaaaaaaaaaaaaa", start)
+	latest := Path(dir, "occ-1234", "bbbbbbbbbbbbbbbbbbbb", start.Add(time.Hour))
+	other := Path(dir, "occ-5678", "abcdefabcdefabcdef", start)
+	for _, path := range []string{older, latest, other} {
+		f, err := Create(path)
+		if err != nil {
+			t.Fatalf("Failed to create recording: %v", err)
+		}
+		f.Close()
+	}
+	if info, _ := os.Stat(latest); info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected recordings to only be readable by their owner, got %v", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name     string
+		session  string
+		expected string
+		notFound bool
+	}{
+		{name: "session name", session: "occ-1234", expected: latest},
+		{name: "cluster ID", session: "1234", expected: latest},
+		{name: "session ID", session: "aaaaaaaaaaaaaaaaaaaaaaaaaaa", expected: older},
+		{name: "session ID prefix", session: "abcdef", expected: other},
+		{name: "path", session: older, expected: older},
+		{name: "not found", session: "9999", notFound: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := Find(dir, tc.session)
+			if tc.notFound {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("Expected ErrNotFound, got %v %v", path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if path != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, path)
+			}
+		})
+	}
+
+	if _, err := Find(dir, "a"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected an ambiguous session ID to fail, got %v", err)
+	}
+	if filepath.Dir(filepath.Dir(latest)) != dir {
+		t.Fatalf("Expected recordings in a directory per session, got %v", latest)
+	}
+}
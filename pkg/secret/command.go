@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CommandTimeout bounds how long a token command, which may prompt to unlock a vault, can run
+const CommandTimeout = 2 * time.Minute
+
+// FromCommand runs the shell command and returns what it prints, such as
+// `pass show ocm/token` or `op read op://Private/ocm/token`. It's run by sh, or cmd on Windows.
+func FromCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, CommandTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	name, args := shell(runtime.GOOS, command)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("token command timed out after %v", CommandTimeout)
+		}
+		return "", fmt.Errorf("token command failed: %v", err)
+	}
+
+	value := strings.TrimSpace(stdout.String())
+	if value == "" {
+		return "", errors.New("token command printed nothing")
+	}
+	return value, nil
+}
+
+// shell returns the program and arguments that run the command in the shell of goos
+func shell(goos string, command string) (string, []string) {
+	if goos == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
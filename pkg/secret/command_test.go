@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFromCommand(t *testing.T) {
+	type test struct {
+		name        string
+		command     string
+		expected    string
+		expectedErr string
+	}
+
+	tests := []test{
+		{name: "prints the token", command: "echo ' token '", expected: "token"},
+		{name: "command fails", command: "exit 3", expectedErr: "token command failed"},
+		{name: "prints nothing", command: "true", expectedErr: "printed nothing"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := FromCommand(context.Background(), tc.command)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil || value != tc.expected {
+				t.Fatalf("Expected %q, got %q (%v)", tc.expected, value, err)
+			}
+		})
+	}
+}
+
+func TestShell(t *testing.T) {
+	for goos, expected := range map[string][]string{
+		"linux":   {"sh", "-c", "pass show ocm"},
+		"darwin":  {"sh", "-c", "pass show ocm"},
+		"windows": {"cmd", "/C", "pass show ocm"},
+	} {
+		name, args := shell(goos, "pass show ocm")
+		if got := append([]string{name}, args...); strings.Join(got, " ") != strings.Join(expected, " ") {
+			t.Errorf("Expected %v on %v, got %v", expected, goos, got)
+		}
+	}
+}
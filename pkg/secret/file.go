@@ -0,0 +1,178 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	"golang.org/x/term"
+)
+
+// fileStore keeps secrets as JSON in an age file encrypted with a passphrase.
+// The passphrase is only asked for, and the file decrypted, once per store.
+type fileStore struct {
+	path       string
+	passphrase func() (string, error)
+	cached     string
+	secrets    map[string]string
+	// workFactor overrides age's scrypt work factor when set, to keep tests quick
+	workFactor int
+}
+
+// NewFileStore returns a store backed by the encrypted file at path
+func NewFileStore(path string, passphrase func() (string, error)) Store {
+	return &fileStore{path: path, passphrase: passphrase}
+}
+
+// PassphraseFromEnvOrPrompt reads the passphrase from PassphraseEnv, or prompts for it on the terminal
+func PassphraseFromEnvOrPrompt() (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to prompt for the secret store passphrase, set %v instead", PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, "Secret store passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
+
+func (f *fileStore) getPassphrase() (string, error) {
+	if f.cached != "" {
+		return f.cached, nil
+	}
+	passphrase, err := f.passphrase()
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the secret store passphrase can't be empty")
+	}
+	f.cached = passphrase
+	return passphrase, nil
+}
+
+func (f *fileStore) Get(key string) (string, error) {
+	secrets, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *fileStore) Set(key string, value string) error {
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.write(secrets)
+}
+
+func (f *fileStore) Delete(key string) error {
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.write(secrets)
+}
+
+func (f *fileStore) read() (map[string]string, error) {
+	if f.secrets != nil {
+		return f.secrets, nil
+	}
+
+	secrets := map[string]string{}
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := f.getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %v: %v", f.path, err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %v: %v", f.path, err)
+	}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %v", f.path, err)
+	}
+	f.secrets = secrets
+	return secrets, nil
+}
+
+func (f *fileStore) write(secrets map[string]string) error {
+	passphrase, err := f.getPassphrase()
+	if err != nil {
+		return err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+	if f.workFactor != 0 {
+		recipient.SetWorkFactor(f.workFactor)
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a failed write can't lose the existing secrets
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+	f.secrets = secrets
+	return nil
+}
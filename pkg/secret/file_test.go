@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func passphrase(p string) func() (string, error) {
+	return func() (string, error) { return p, nil }
+}
+
+func testFileStore(path string, p string) Store {
+	store := NewFileStore(path, passphrase(p))
+	store.(*fileStore).workFactor = 10
+	return store
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "occ", "secrets.age")
+	store := testFileStore(path, "correct horse")
+
+	if _, err := store.Get("offline_access_token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound from an empty store, got %v", err)
+	}
+
+	if err := store.Set("offline_access_token", "token"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err := store.Set("offline_access_token/staging", "staging-token"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the secrets file to be written, got %v", err)
+	}
+	if strings.Contains(string(data), "token") {
+		t.Fatalf("Expected the secrets file to be encrypted")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the secrets file to only be readable by its owner, got %v", info.Mode())
+	}
+
+	reopened := testFileStore(path, "correct horse")
+	if value, err := reopened.Get("offline_access_token/staging"); err != nil || value != "staging-token" {
+		t.Fatalf("Expected staging-token, got %v (%v)", value, err)
+	}
+
+	if err := reopened.Delete("offline_access_token/staging"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if _, err := reopened.Get("offline_access_token/staging"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if value, err := reopened.Get("offline_access_token"); err != nil || value != "token" {
+		t.Fatalf("Expected token, got %v (%v)", value, err)
+	}
+
+	wrong := testFileStore(path, "wrong")
+	if _, err := wrong.Get("offline_access_token"); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Fatalf("Expected a decryption error, got %v", err)
+	}
+}
+
+func TestFileStoreEmptyPassphrase(t *testing.T) {
+	store := testFileStore(filepath.Join(t.TempDir(), "secrets.age"), "")
+	if err := store.Set("key", "value"); err == nil {
+		t.Fatalf("Expected an error for an empty passphrase")
+	}
+}
@@ -0,0 +1,38 @@
+package secret
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeyringStore(t *testing.T) {
+	keyring.MockInit()
+	store, err := New(Keyring, "")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if _, err := store.Get("offline_access_token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := store.Set("offline_access_token", "token"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if value, err := store.Get("offline_access_token"); err != nil || value != "token" {
+		t.Fatalf("Expected token, got %v (%v)", value, err)
+	}
+	if err := store.Delete("offline_access_token"); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err := store.Delete("offline_access_token"); err != nil {
+		t.Fatalf("Expected deleting a missing secret to succeed, got %v", err)
+	}
+}
+
+func TestNewUnknownStore(t *testing.T) {
+	if _, err := New("vault", ""); err == nil {
+		t.Fatalf("Expected an error for an unknown store")
+	}
+}
@@ -0,0 +1,47 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Keyring keeps secrets in the OS keyring, such as the Secret Service on Linux or the macOS Keychain
+	Keyring = "keyring"
+
+	// File keeps secrets in a passphrase encrypted file, for hosts without a keyring
+	File = "file"
+
+	// Config keeps secrets in plain text in the occ config file
+	Config = "config"
+
+	// Service is the name occ's secrets are filed under in the OS keyring
+	Service = "occ"
+
+	// PassphraseEnv holds the passphrase of the encrypted file store, it's prompted for otherwise
+	PassphraseEnv = "OCC_SECRET_PASSPHRASE"
+)
+
+var (
+	// ErrNotFound is returned by Get when the store doesn't hold the secret
+	ErrNotFound = errors.New("secret not found")
+)
+
+// Store keeps secrets by key
+type Store interface {
+	Get(key string) (string, error)
+	Set(key string, value string) error
+	Delete(key string) error
+}
+
+// New returns the named store. path is only used by the file store.
+func New(name string, path string) (Store, error) {
+	switch name {
+	case Keyring, "":
+		return keyringStore{}, nil
+	case File:
+		return NewFileStore(path, PassphraseFromEnvOrPrompt), nil
+	default:
+		return nil, fmt.Errorf("unknown secret store %q, expected %v, %v or %v", name, Keyring, File, Config)
+	}
+}
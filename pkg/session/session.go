@@ -0,0 +1,228 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/openshift/occ/pkg/engine"
+)
+
+const (
+	// NamePrefix is prepended to every session's container name
+	NamePrefix = "occ-"
+
+	// ManagedLabel marks a container as an occ session
+	ManagedLabel = "io.openshift.occ.managed"
+
+	// ClusterLabel holds the cluster ID the session was started for, if any
+	ClusterLabel = "io.openshift.occ.cluster"
+
+	// SessionLabel holds the name of the session a secret belongs to
+	SessionLabel = "io.openshift.occ.session"
+
+	// createGracePeriod keeps prunes away from sessions, and their secrets, still being created
+	createGracePeriod = time.Minute
+)
+
+var (
+	// ErrNotFound is returned when no running session matches the requested name or cluster
+	ErrNotFound = errors.New("no occ session found")
+
+	// container names may only contain these characters, anything else is replaced with a dash
+	invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// Session is a running occ container
+type Session struct {
+	Name      string
+	ID        string
+	ClusterID string
+	Image     string
+	State     string
+	Created   time.Time
+}
+
+// Name returns the stable container name for a session on the given cluster.
+// Sessions started without a cluster get a random name instead.
+func Name(clusterID string) string {
+	if clusterID == "" {
+		suffix := make([]byte, 4)
+		_, _ = rand.Read(suffix)
+		return NamePrefix + hex.EncodeToString(suffix)
+	}
+	return NamePrefix + strings.Trim(invalidNameChars.ReplaceAllString(clusterID, "-"), "-")
+}
+
+// Labels returns the labels that mark a container as an occ session
+func Labels(clusterID string) map[string]string {
+	labels := map[string]string{
+		ManagedLabel: "true",
+	}
+	if clusterID != "" {
+		labels[ClusterLabel] = clusterID
+	}
+	return labels
+}
+
+// List returns all occ sessions known to the engine
+func List(ctx context.Context, eng engine.Engine) ([]Session, error) {
+	ctrs, err := eng.List(ctx, map[string]string{ManagedLabel: "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(ctrs))
+	for _, ctr := range ctrs {
+		sessions = append(sessions, fromContainer(ctr))
+	}
+	return sessions, nil
+}
+
+// Find returns the session matching the given session name or cluster ID
+func Find(ctx context.Context, eng engine.Engine, nameOrCluster string) (Session, error) {
+	sessions, err := List(ctx, eng)
+	if err != nil {
+		return Session{}, err
+	}
+	return match(sessions, nameOrCluster)
+}
+
+func match(sessions []Session, nameOrCluster string) (Session, error) {
+	for _, s := range sessions {
+		if s.Name == nameOrCluster || s.Name == NamePrefix+nameOrCluster || s.ID == nameOrCluster {
+			return s, nil
+		}
+	}
+
+	var found []Session
+	for _, s := range sessions {
+		if s.ClusterID == nameOrCluster {
+			found = append(found, s)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Session{}, fmt.Errorf("%w matching %v", ErrNotFound, nameOrCluster)
+	case 1:
+		return found[0], nil
+	default:
+		return Session{}, fmt.Errorf("multiple occ sessions found for cluster %v, use the session name instead", nameOrCluster)
+	}
+}
+
+func fromContainer(ctr engine.Container) Session {
+	return Session{
+		Name:      ctr.Name,
+		ID:        ctr.ID,
+		ClusterID: ctr.Labels[ClusterLabel],
+		Image:     ctr.Image,
+		State:     ctr.State,
+		Created:   ctr.Created,
+	}
+}
+
+// Stop stops the session and makes sure it's removed along with its secrets, keeping
+// sessions ephemeral even if the engine's automatic removal didn't kick in.
+func Stop(ctx context.Context, eng engine.Engine, s Session, timeout uint) error {
+	if err := eng.Stop(ctx, s.ID, timeout); err != nil {
+		return fmt.Errorf("failed to stop session: %v", err)
+	}
+
+	if err := eng.Remove(ctx, s.ID, true); err != nil {
+		return fmt.Errorf("failed to remove session: %v", err)
+	}
+	return RemoveSecrets(ctx, eng, s.Name)
+}
+
+// activeStates are the states of sessions that are running, or about to change state
+var activeStates = map[string]bool{"running": true, "paused": true, "restarting": true, "stopping": true, "removing": true}
+
+// Stale returns the sessions that aren't running, such as ones left behind when occ crashed before
+// starting them. Recent sessions are left alone, another occ may be about to start them.
+func Stale(sessions []Session, now time.Time) []Session {
+	var stale []Session
+	for _, s := range sessions {
+		if activeStates[s.State] || now.Sub(s.Created) < createGracePeriod {
+			continue
+		}
+		stale = append(stale, s)
+	}
+	return stale
+}
+
+// SecretName returns the name of the engine secret holding the session's env variable
+func SecretName(sessionName string, envName string) string {
+	return sessionName + "-" + strings.ToLower(strings.ReplaceAll(envName, "_", "-"))
+}
+
+// SecretLabels returns the labels that tie a secret to its session
+func SecretLabels(sessionName string) map[string]string {
+	return map[string]string{
+		ManagedLabel: "true",
+		SessionLabel: sessionName,
+	}
+}
+
+// RemoveSecrets removes every secret belonging to the session
+func RemoveSecrets(ctx context.Context, eng engine.Engine, sessionName string) error {
+	secrets, err := eng.ListSecrets(ctx, SecretLabels(sessionName))
+	if err != nil {
+		return fmt.Errorf("failed to list session secrets: %v", err)
+	}
+	for _, secret := range secrets {
+		if err := eng.RemoveSecret(ctx, secret.Name); err != nil {
+			return fmt.Errorf("failed to remove session secret %v: %v", secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// PruneSecrets removes the secrets left behind by sessions that no longer exist,
+// such as ones that crashed, and returns their names
+func PruneSecrets(ctx context.Context, eng engine.Engine) ([]string, error) {
+	secrets, err := eng.ListSecrets(ctx, map[string]string{ManagedLabel: "true"})
+	if err != nil || len(secrets) == 0 {
+		return nil, err
+	}
+
+	sessions, err := List(ctx, eng)
+	if err != nil {
+		return nil, err
+	}
+	return pruneSecrets(ctx, eng, orphanedSecrets(secrets, sessions, time.Now()))
+}
+
+// orphanedSecrets returns the secrets whose session is gone. Recent secrets are left alone,
+// their session may still be being created by another occ.
+func orphanedSecrets(secrets []engine.Secret, sessions []Session, now time.Time) []engine.Secret {
+	live := map[string]bool{}
+	for _, s := range sessions {
+		live[s.Name] = true
+	}
+
+	var orphaned []engine.Secret
+	for _, secret := range secrets {
+		if live[secret.Labels[SessionLabel]] || now.Sub(secret.Created) < createGracePeriod {
+			continue
+		}
+		orphaned = append(orphaned, secret)
+	}
+	return orphaned
+}
+
+func pruneSecrets(ctx context.Context, eng engine.Engine, secrets []engine.Secret) ([]string, error) {
+	var removed []string
+	for _, secret := range secrets {
+		if err := eng.RemoveSecret(ctx, secret.Name); err != nil {
+			return removed, fmt.Errorf("failed to remove secret %v: %v", secret.Name, err)
+		}
+		removed = append(removed, secret.Name)
+	}
+	return removed, nil
+}
@@ -0,0 +1,140 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/occ/pkg/engine"
+)
+
+func TestName(t *testing.T) {
+	type test struct {
+		name      string
+		clusterID string
+		expected  string
+	}
+
+	tests := []test{
+		{name: "cluster id", clusterID: "1a2b3c4d5e", expected: "occ-1a2b3c4d5e"},
+		{name: "cluster name with invalid characters", clusterID: "my cluster/prod", expected: "occ-my-cluster-prod"},
+		{name: "leading invalid characters", clusterID: "@@prod", expected: "occ-prod"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := Name(tc.clusterID); result != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+
+	t.Run("no cluster id", func(t *testing.T) {
+		first, second := Name(""), Name("")
+		if !strings.HasPrefix(first, NamePrefix) || len(first) != len(NamePrefix)+8 {
+			t.Fatalf("Expected a random session name, got %v", first)
+		}
+		if first == second {
+			t.Fatalf("Expected random session names to differ, got %v twice", first)
+		}
+	})
+}
+
+func TestLabels(t *testing.T) {
+	labels := Labels("1234")
+	if labels[ManagedLabel] != "true" {
+		t.Fatalf("Expected %v to be true, got %v", ManagedLabel, labels[ManagedLabel])
+	}
+	if labels[ClusterLabel] != "1234" {
+		t.Fatalf("Expected %v to be 1234, got %v", ClusterLabel, labels[ClusterLabel])
+	}
+
+	if _, ok := Labels("")[ClusterLabel]; ok {
+		t.Fatalf("Expected no %v label without a cluster id", ClusterLabel)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	sessions := []Session{
+		{Name: "occ-abc", ID: "id-abc", ClusterID: "abc"},
+		{Name: "occ-renamed", ID: "id-def", ClusterID: "def"},
+		{Name: "occ-1234abcd", ID: "id-none"},
+		{Name: "occ-dup-1", ID: "id-dup-1", ClusterID: "dup"},
+		{Name: "occ-dup-2", ID: "id-dup-2", ClusterID: "dup"},
+	}
+
+	type test struct {
+		name          string
+		nameOrCluster string
+		expectedID    string
+		expectedError string
+	}
+
+	tests := []test{
+		{name: "full session name", nameOrCluster: "occ-abc", expectedID: "id-abc"},
+		{name: "session name without prefix", nameOrCluster: "1234abcd", expectedID: "id-none"},
+		{name: "container id", nameOrCluster: "id-def", expectedID: "id-def"},
+		{name: "cluster id", nameOrCluster: "def", expectedID: "id-def"},
+		{name: "no match", nameOrCluster: "nope", expectedError: "no occ session found matching nope"},
+		{name: "ambiguous cluster", nameOrCluster: "dup", expectedError: "multiple occ sessions found for cluster dup, use the session name instead"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := match(sessions, tc.nameOrCluster)
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if result.ID != tc.expectedID {
+				t.Fatalf("Expected %v, got %v", tc.expectedID, result.ID)
+			}
+		})
+	}
+
+	if _, err := match(sessions, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSecretName(t *testing.T) {
+	if result := SecretName("occ-1234", "OFFLINE_ACCESS_TOKEN"); result != "occ-1234-offline-access-token" {
+		t.Fatalf("Unexpected secret name %v", result)
+	}
+}
+
+func TestOrphanedSecrets(t *testing.T) {
+	now := time.Now()
+	secrets := []engine.Secret{
+		{Name: "occ-live-token", Created: now.Add(-time.Hour), Labels: map[string]string{SessionLabel: "occ-live"}},
+		{Name: "occ-crashed-token", Created: now.Add(-time.Hour), Labels: map[string]string{SessionLabel: "occ-crashed"}},
+		{Name: "occ-starting-token", Created: now.Add(-time.Second), Labels: map[string]string{SessionLabel: "occ-starting"}},
+	}
+	sessions := []Session{{Name: "occ-live"}}
+
+	orphaned := orphanedSecrets(secrets, sessions, now)
+	if len(orphaned) != 1 || orphaned[0].Name != "occ-crashed-token" {
+		t.Fatalf("Expected only the crashed session's secret, got %v", orphaned)
+	}
+}
+
+func TestStale(t *testing.T) {
+	now := time.Now()
+	sessions := []Session{
+		{Name: "occ-running", State: "running", Created: now.Add(-time.Hour)},
+		{Name: "occ-crashed", State: "created", Created: now.Add(-time.Hour)},
+		{Name: "occ-exited", State: "exited", Created: now.Add(-time.Hour)},
+		{Name: "occ-starting", State: "created", Created: now.Add(-time.Second)},
+	}
+
+	stale := Stale(sessions, now)
+	if len(stale) != 2 || stale[0].Name != "occ-crashed" || stale[1].Name != "occ-exited" {
+		t.Fatalf("Expected the crashed and exited sessions, got %v", stale)
+	}
+}